@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// AdminHandler returns the admin handler of the default Manager, see
+// Manager.AdminHandler.
+func AdminHandler() http.Handler {
+	return defaultManager.AdminHandler()
+}
+
+// AdminHandler returns an http.Handler to inspect and change the logging of
+// the Manager at runtime. Mount it with its prefix stripped:
+//
+//	mux.Handle("/debug/logger/", http.StripPrefix("/debug/logger", logger.AdminHandler()))
+//
+// Routes:
+//
+//	GET  /         config, level, streams and stats together
+//	GET  /config   effective configuration
+//	PUT  /config   merge a partial configuration, e.g. {"detail":{"rawData":true}}
+//	GET  /level    app log level, {"level":"info"}
+//	PUT  /level    change the app log level, {"level":"debug"}
+//	GET  /streams  output switches, {"app":true,"detail":true,"summary":true}
+//	PUT  /streams  turn streams on or off, {"detail":false}
+//	GET  /stats    written, dropped, spilled and queued entries per stream
+//
+// The level, streams and config routes act on the Manager only; the stats
+// are process-wide, see Stats.
+func (m *Manager) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, map[string]interface{}{
+			"config":  m.Config(),
+			"level":   m.level.Level().String(),
+			"streams": m.streamStates(),
+			"stats":   Stats(),
+		})
+	})
+	mux.HandleFunc("GET /config", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, m.Config())
+	})
+	mux.HandleFunc("PUT /config", func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		cfg, err := decodeLogConfig(b)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := m.Load(cfg); err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, m.Config())
+	})
+	mux.HandleFunc("GET /level", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, map[string]string{"level": m.level.Level().String()})
+	})
+	mux.HandleFunc("PUT /level", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Level *zapcore.Level `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Level == nil {
+			writeAdminError(w, http.StatusBadRequest, errors.New("level: missing"))
+			return
+		}
+		if err := m.Load(LogConfig{}, WithAppLogLevel(*req.Level)); err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]string{"level": m.level.Level().String()})
+	})
+	mux.HandleFunc("GET /streams", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, m.streamStates())
+	})
+	mux.HandleFunc("PUT /streams", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]bool
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		for stream := range req {
+			if m.streams.get(stream) == nil {
+				writeAdminError(w, http.StatusBadRequest, fmt.Errorf("unknown stream %q", stream))
+				return
+			}
+		}
+		for stream, on := range req {
+			m.SetStreamEnabled(stream, on)
+		}
+		writeAdminJSON(w, http.StatusOK, m.streamStates())
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, Stats())
+	})
+	return mux
+}
+
+func (m *Manager) streamStates() map[string]bool {
+	return map[string]bool{
+		StreamApp:     m.StreamEnabled(StreamApp),
+		StreamDetail:  m.StreamEnabled(StreamDetail),
+		StreamSummary: m.StreamEnabled(StreamSummary),
+	}
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set(ContentType, ContentTypeJSON)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	writeAdminJSON(w, status, map[string]string{"error": err.Error()})
+}
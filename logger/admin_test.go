@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func adminRequest(t *testing.T, h http.Handler, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, "/debug/logger"+path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestAdminHandler(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{ProjectName: "test_project", Sinks: Sinks{Detail: []Sink{sink}}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	h := http.StripPrefix("/debug/logger", m.AdminHandler())
+
+	code, resp := adminRequest(t, h, http.MethodGet, "/config", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "test_project", resp["projectName"])
+
+	// raw data off through a partial config
+	code, resp = adminRequest(t, h, http.MethodPut, "/config", `{"detail":{"rawData":false}}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, resp["detail"].(map[string]interface{})["rawData"])
+	assert.False(t, m.Config().Detail.RawData)
+
+	code, resp = adminRequest(t, h, http.MethodPut, "/config", `{"detail":{"rawdata":"yes"}}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, resp["error"], "rawdata")
+
+	// level
+	code, resp = adminRequest(t, h, http.MethodPut, "/level", `{"level":"warn"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "warn", resp["level"])
+	assert.Equal(t, zapcore.WarnLevel, m.level.Level())
+	assert.Equal(t, zapcore.WarnLevel, m.Config().AppLog.LogLevel)
+
+	code, _ = adminRequest(t, h, http.MethodPut, "/level", `{"level":"loud"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	_, resp = adminRequest(t, h, http.MethodGet, "/level", "")
+	assert.Equal(t, "warn", resp["level"])
+
+	// streams
+	code, resp = adminRequest(t, h, http.MethodPut, "/streams", `{"detail":false}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"app": true, "detail": false, "summary": true}, resp)
+
+	dl := m.NewDetailLog("session", "", "scenario")
+	dl.AddInputRequest("client", "cmd", "invoke", nil, map[string]string{"a": "b"})
+	dl.End()
+	assert.Empty(t, sink.Entries())
+
+	code, _ = adminRequest(t, h, http.MethodPut, "/streams", `{"audit":false}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	adminRequest(t, h, http.MethodPut, "/streams", `{"detail":true}`)
+	dl = m.NewDetailLog("session", "", "scenario")
+	dl.AddInputRequest("client", "cmd", "invoke", nil, map[string]string{"a": "b"})
+	dl.End()
+	assert.Len(t, sink.Entries(), 1)
+
+	// stats and overview
+	code, resp = adminRequest(t, h, http.MethodGet, "/stats", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, resp, StreamDetail)
+	assert.Contains(t, resp, StreamSummary)
+
+	code, resp = adminRequest(t, h, http.MethodGet, "/", "")
+	assert.Equal(t, http.StatusOK, code)
+	for _, key := range []string{"config", "level", "streams", "stats"} {
+		assert.Contains(t, resp, key)
+	}
+}
+
+func TestStreamSwitchApp(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{Sinks: Sinks{App: []Sink{sink}}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	l := m.NewLogger().With()
+
+	captureStdout(t, func() {
+		l.Info("first")
+		m.SetStreamEnabled(StreamApp, false)
+		l.Info("second")
+		m.SetStreamEnabled(StreamApp, true)
+		l.Info("third")
+	})
+
+	assert.Len(t, sink.Entries(), 2)
+	assert.Error(t, m.SetStreamEnabled("audit", false))
+}
@@ -10,16 +10,13 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
- 
 func NewLogger(options ...zap.Option) *zap.Logger {
-	zap.NewProduction()
-	if configLog.AppLog.LogFile {
-		if err := ensureLogDirExists(configLog.AppLog.Name); err != nil {
-			log.Fatal(err)
-		}
-		return newLogFile(configLog.AppLog.Name)
-	}
+	return defaultManager.NewLogger(options...)
+}
 
+// NewLogger creates an app logger with the configuration of the Manager.
+func (m *Manager) NewLogger(options ...zap.Option) *zap.Logger {
+	conf := m.config()
 	encCfg := zapcore.EncoderConfig{
 		MessageKey:   "msg",
 		TimeKey:      "time",
@@ -28,17 +25,34 @@ func NewLogger(options ...zap.Option) *zap.Logger {
 		EncodeCaller: zapcore.ShortCallerEncoder,
 	}
 
-	if configLog.AppLog.LogLevel == 0 {
-		configLog.AppLog.LogLevel = zapcore.DebugLevel
+	// the level of the Manager is applied by appCore so that it follows
+	// reloads and can be escalated per transaction
+	level := zapcore.DebugLevel
+
+	if conf.AppLog.LogFile {
+		if err := ensureLogDirExists(conf.AppLog.Name); err != nil {
+			log.Fatal(err)
+		}
+		fileLog := newLogFile(conf.ProjectName, conf.AppLog.Name)
+		if fileLog == nil {
+			return fileLog
+		}
+		if len(conf.Sinks.App) == 0 {
+			return fileLog.WithOptions(append([]zap.Option{zap.WrapCore(m.appCore)}, options...)...)
+		}
+		cores := append([]zapcore.Core{fileLog.Core()}, sinkCores(encCfg, level, conf.Sinks.App)...)
+		return zap.New(m.appCore(zapcore.NewTee(cores...)), options...)
 	}
 
 	// File encoder using console format
 	consoleEncoder := zapcore.NewConsoleEncoder(encCfg)
 
 	// Create a zapcore core
-	core := zapcore.NewTee(
-		zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), configLog.AppLog.LogLevel),
-	)
+	cores := []zapcore.Core{
+		zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), level),
+	}
+	cores = append(cores, sinkCores(encCfg, level, conf.Sinks.App)...)
+	core := m.appCore(zapcore.NewTee(cores...))
 
 	// Create logger
 	log := zap.New(core, options...)
@@ -46,6 +60,50 @@ func NewLogger(options ...zap.Option) *zap.Logger {
 
 }
 
+// appCore applies the level of the Manager, unless escalated by DebugLogger,
+// and drops every entry while the app stream is turned off.
+func (m *Manager) appCore(core zapcore.Core) zapcore.Core {
+	return appCore{Core: core, streams: m.streams, level: m.level}
+}
+
+type appCore struct {
+	zapcore.Core
+	streams *streamSwitches
+	level   zapcore.LevelEnabler
+	debug   bool
+}
+
+func (c appCore) Enabled(level zapcore.Level) bool {
+	if c.streams.disabled(StreamApp) {
+		return false
+	}
+	return (c.debug || c.level.Enabled(level)) && c.Core.Enabled(level)
+}
+
+func (c appCore) With(fields []zapcore.Field) zapcore.Core {
+	c.Core = c.Core.With(fields)
+	return c
+}
+
+func (c appCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
+
+// sinkCores encodes app entries for sinks as JSON with a readable level and
+// time, unlike the console output.
+func sinkCores(encCfg zapcore.EncoderConfig, level zapcore.LevelEnabler, sinks []Sink) []zapcore.Core {
+	encCfg.EncodeLevel = zapcore.LowercaseLevelEncoder
+	encCfg.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	cores := make([]zapcore.Core, 0, len(sinks))
+	for _, sink := range sinks {
+		cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(encCfg), sinkWriter{sink: sink, stream: StreamApp}, level))
+	}
+	return cores
+}
+
 func NewLog(c context.Context) *zap.Logger {
 	switch logger := c.Value(key).(type) {
 	case *zap.Logger:
@@ -56,6 +114,17 @@ func NewLog(c context.Context) *zap.Logger {
 }
 
 func InitSession(c context.Context, logger *zap.Logger) (context.Context, *zap.Logger) {
+	return defaultManager.InitSession(c, logger)
+}
+
+// InitSession binds the session of c to logger, falling back to a new app
+// logger of the Manager when logger is nil. The logger runs at debug level
+// when c is flagged by WithDebug.
+func (m *Manager) InitSession(c context.Context, logger *zap.Logger) (context.Context, *zap.Logger) {
+	if logger == nil {
+		logger = m.NewLogger()
+	}
+
 	// get session from context
 	session := c.Value(xSession)
 	if session == nil {
@@ -71,6 +140,12 @@ func InitSession(c context.Context, logger *zap.Logger) (context.Context, *zap.L
 
 	// set session to logger
 	l := logger.With(zap.String("session", c.Value(xSession).(string)))
+	if tc, ok := TraceContextFromContext(c); ok {
+		l = l.With(zap.String("traceId", tc.TraceID), zap.String("spanId", tc.SpanID))
+	}
+	if IsDebug(c) {
+		l = DebugLogger(l)
+	}
 	// set logger to context
 	c = context.WithValue(c, key, l)
 	return c, l
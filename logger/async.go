@@ -0,0 +1,589 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type OverflowPolicy string
+
+const (
+	// OverflowBlock makes End wait until there is room in the buffer.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropNewest discards the entry being written.
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+	// OverflowDropOldest discards the oldest buffered entry.
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowSpill appends the entry to a spill file in SpillDir.
+	OverflowSpill OverflowPolicy = "spill"
+)
+
+// AsyncConfig moves detail and summary writes off the request goroutine.
+type AsyncConfig struct {
+	BufferSize    int            `json:"bufferSize"`
+	Workers       int            `json:"workers"`
+	BatchSize     int            `json:"batchSize"`
+	FlushInterval time.Duration  `json:"flushInterval"`
+	Policy        OverflowPolicy `json:"policy"`
+	SpillDir      string         `json:"spillDir"`
+}
+
+type StreamStats struct {
+	Written int64 `json:"written"`
+	Dropped int64 `json:"dropped"`
+	Spilled int64 `json:"spilled"`
+	Queued  int   `json:"queued"`
+}
+
+type streamCounter struct {
+	written atomic.Int64
+	dropped atomic.Int64
+	spilled atomic.Int64
+}
+
+var streamCounters = map[string]*streamCounter{
+	StreamDetail:  {},
+	StreamSummary: {},
+}
+
+// countDropped counts entries dropped by a sink in the counters of their
+// stream.
+func countDropped(entries ...Entry) {
+	for _, entry := range entries {
+		if c := streamCounters[entry.Stream]; c != nil {
+			c.dropped.Add(1)
+		}
+	}
+}
+
+// asyncKey identifies a pipeline: each stream of each AsyncConfig gets its own.
+type asyncKey struct {
+	stream string
+	conf   *AsyncConfig
+}
+
+var asyncWriters = struct {
+	sync.Mutex
+	m map[asyncKey]*asyncWriter
+}{m: map[asyncKey]*asyncWriter{}}
+
+func (c AsyncConfig) withDefaults() AsyncConfig {
+	if c.BufferSize <= 0 {
+		c.BufferSize = 1024
+	}
+	if c.Workers <= 0 {
+		c.Workers = 1
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 64
+	}
+	if c.BatchSize > c.BufferSize {
+		c.BatchSize = c.BufferSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = time.Second
+	}
+	if c.Policy == "" {
+		c.Policy = OverflowBlock
+	}
+	if c.Policy == OverflowSpill && c.SpillDir == "" {
+		c.SpillDir = "./logs/spill"
+	}
+	return c
+}
+
+func (c AsyncConfig) validate() error {
+	var errs []error
+	if c.BufferSize < 0 {
+		errs = append(errs, fmt.Errorf("bufferSize: must not be negative, got %d", c.BufferSize))
+	}
+	if c.Workers < 0 {
+		errs = append(errs, fmt.Errorf("workers: must not be negative, got %d", c.Workers))
+	}
+	if c.BatchSize < 0 {
+		errs = append(errs, fmt.Errorf("batchSize: must not be negative, got %d", c.BatchSize))
+	}
+	if c.BufferSize > 0 && c.BatchSize > c.BufferSize {
+		errs = append(errs, fmt.Errorf("batchSize: %d is larger than bufferSize %d", c.BatchSize, c.BufferSize))
+	}
+	if c.FlushInterval < 0 {
+		errs = append(errs, fmt.Errorf("flushInterval: must not be negative, got %s", c.FlushInterval))
+	}
+	switch c.Policy {
+	case "", OverflowBlock, OverflowDropNewest, OverflowDropOldest, OverflowSpill:
+	default:
+		errs = append(errs, fmt.Errorf("policy: unknown overflow policy %q", c.Policy))
+	}
+	if c.Policy != OverflowSpill && c.SpillDir != "" {
+		errs = append(errs, fmt.Errorf("spillDir: only used with the %q policy", OverflowSpill))
+	}
+	return errors.Join(errs...)
+}
+
+type asyncConfigJSON struct {
+	BufferSize    int             `json:"bufferSize"`
+	Workers       int             `json:"workers"`
+	BatchSize     int             `json:"batchSize"`
+	FlushInterval json.RawMessage `json:"flushInterval,omitempty"`
+	Policy        OverflowPolicy  `json:"policy"`
+	SpillDir      string          `json:"spillDir"`
+}
+
+// MarshalJSON writes the flush interval as a duration string such as "1s".
+func (c AsyncConfig) MarshalJSON() ([]byte, error) {
+	interval, _ := json.Marshal(c.FlushInterval.String())
+	return json.Marshal(asyncConfigJSON{
+		BufferSize:    c.BufferSize,
+		Workers:       c.Workers,
+		BatchSize:     c.BatchSize,
+		FlushInterval: interval,
+		Policy:        c.Policy,
+		SpillDir:      c.SpillDir,
+	})
+}
+
+// UnmarshalJSON accepts the flush interval either as a duration string such
+// as "500ms" or as a number of nanoseconds.
+func (c *AsyncConfig) UnmarshalJSON(b []byte) error {
+	var v asyncConfigJSON
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	*c = AsyncConfig{
+		BufferSize: v.BufferSize,
+		Workers:    v.Workers,
+		BatchSize:  v.BatchSize,
+		Policy:     v.Policy,
+		SpillDir:   v.SpillDir,
+	}
+	if len(v.FlushInterval) == 0 {
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(v.FlushInterval, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(v.FlushInterval, &n); err != nil {
+			return fmt.Errorf("flushInterval: %s is not a duration", v.FlushInterval)
+		}
+		c.FlushInterval = time.Duration(n)
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("flushInterval: %w", err)
+	}
+	c.FlushInterval = d
+	return nil
+}
+
+type asyncItem struct {
+	entry Entry
+	sinks []Sink
+}
+
+type asyncWriter struct {
+	stream  string
+	project string
+	conf    AsyncConfig
+	counter *streamCounter
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	buf      []asyncItem
+	head     int
+	count    int
+	inflight int
+	draining bool
+	closed   bool
+	seen     []Sink
+	spill    *os.File
+
+	wg   sync.WaitGroup
+	stop chan struct{}
+}
+
+// asyncWriterFor returns the running pipeline of a stream for conf, starting
+// it on first use. It returns nil when conf is nil, meaning entries are
+// written synchronously.
+func asyncWriterFor(stream, project string, conf *AsyncConfig) *asyncWriter {
+	if conf == nil {
+		return nil
+	}
+
+	key := asyncKey{stream: stream, conf: conf}
+	asyncWriters.Lock()
+	defer asyncWriters.Unlock()
+	if a, ok := asyncWriters.m[key]; ok {
+		return a
+	}
+
+	a := newAsyncWriter(stream, project, conf.withDefaults())
+	asyncWriters.m[key] = a
+	return a
+}
+
+func newAsyncWriter(stream, project string, conf AsyncConfig) *asyncWriter {
+	counter := streamCounters[stream]
+	if counter == nil {
+		counter = &streamCounter{}
+	}
+	a := &asyncWriter{
+		stream:  stream,
+		project: project,
+		conf:    conf,
+		counter: counter,
+		buf:     make([]asyncItem, conf.BufferSize),
+		stop:    make(chan struct{}),
+	}
+	a.notEmpty = sync.NewCond(&a.mu)
+	a.notFull = sync.NewCond(&a.mu)
+
+	for i := 0; i < conf.Workers; i++ {
+		a.wg.Add(1)
+		go a.run()
+	}
+	a.wg.Add(1)
+	go a.tick()
+	return a
+}
+
+// dispatch hands the entry to the async pipeline when there is one, or
+// writes it to the sinks directly.
+func dispatch(a *asyncWriter, sinks []Sink, entry Entry) {
+	if a == nil {
+		writeEntry(sinks, entry)
+		return
+	}
+	a.enqueue(asyncItem{entry: entry, sinks: sinks})
+}
+
+func (a *asyncWriter) enqueue(item asyncItem) {
+	a.mu.Lock()
+	for a.count == len(a.buf) && !a.closed {
+		switch a.conf.Policy {
+		case OverflowDropNewest:
+			a.mu.Unlock()
+			a.counter.dropped.Add(1)
+			return
+		case OverflowDropOldest:
+			a.buf[a.head] = asyncItem{}
+			a.head = (a.head + 1) % len(a.buf)
+			a.count--
+			a.counter.dropped.Add(1)
+		case OverflowSpill:
+			err := a.spillLocked(item)
+			a.mu.Unlock()
+			if err != nil {
+				a.counter.dropped.Add(1)
+				fmt.Fprintf(os.Stderr, "Failed to spill %s log: %v\n", a.stream, err)
+				return
+			}
+			a.counter.spilled.Add(1)
+			return
+		default:
+			a.notFull.Wait()
+		}
+	}
+
+	if a.closed {
+		a.mu.Unlock()
+		writeEntry(item.sinks, item.entry)
+		return
+	}
+
+	a.buf[(a.head+a.count)%len(a.buf)] = item
+	a.count++
+	if a.count >= a.conf.BatchSize {
+		a.notEmpty.Signal()
+	}
+	a.mu.Unlock()
+}
+
+func (a *asyncWriter) spillLocked(item asyncItem) error {
+	if a.spill == nil {
+		if err := ensureLogDirExists(a.conf.SpillDir); err != nil {
+			return err
+		}
+		name := filepath.Join(a.conf.SpillDir, fmt.Sprintf("%s_%s_spill.log", a.project, a.stream))
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		a.spill = f
+	}
+
+	line := make([]byte, 0, len(item.entry.Payload)+2)
+	line = append(line, item.entry.Payload...)
+	_, err := a.spill.Write(append(line, endOfLine()...))
+	return err
+}
+
+func (a *asyncWriter) run() {
+	defer a.wg.Done()
+	for {
+		batch, ok := a.next()
+		if !ok {
+			return
+		}
+
+		for _, item := range batch {
+			writeEntry(item.sinks, item.entry)
+		}
+
+		a.mu.Lock()
+		for _, item := range batch {
+			a.seen = appendSinks(a.seen, item.sinks...)
+		}
+		a.inflight -= len(batch)
+		a.mu.Unlock()
+	}
+}
+
+// next waits for a full batch, or for any buffered entries once the flush
+// interval elapsed or a flush was requested.
+func (a *asyncWriter) next() ([]asyncItem, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for {
+		if a.count >= a.conf.BatchSize || (a.count > 0 && (a.draining || a.closed)) {
+			break
+		}
+		if a.count == 0 {
+			a.draining = false
+			if a.closed {
+				return nil, false
+			}
+		}
+		a.notEmpty.Wait()
+	}
+
+	n := min(a.count, a.conf.BatchSize)
+	batch := make([]asyncItem, n)
+	for i := range batch {
+		batch[i] = a.buf[a.head]
+		a.buf[a.head] = asyncItem{}
+		a.head = (a.head + 1) % len(a.buf)
+	}
+	a.count -= n
+	a.inflight += n
+	a.notFull.Broadcast()
+	return batch, true
+}
+
+func (a *asyncWriter) tick() {
+	defer a.wg.Done()
+	ticker := time.NewTicker(a.conf.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			a.drain()
+		}
+	}
+}
+
+func (a *asyncWriter) drain() {
+	a.mu.Lock()
+	a.draining = true
+	a.notEmpty.Broadcast()
+	a.mu.Unlock()
+}
+
+func (a *asyncWriter) idle() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.count == 0 && a.inflight == 0
+}
+
+// flush waits until every buffered entry has been written, then flushes the
+// sinks that received them.
+func (a *asyncWriter) flush(ctx context.Context) error {
+	a.drain()
+	for !a.idle() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("flush %s log: %w", a.stream, ctx.Err())
+		case <-time.After(5 * time.Millisecond):
+			a.drain()
+		}
+	}
+
+	a.mu.Lock()
+	sinks := append([]Sink(nil), a.seen...)
+	var errs []error
+	if a.spill != nil {
+		errs = append(errs, a.spill.Sync())
+	}
+	a.mu.Unlock()
+
+	for _, sink := range sinks {
+		errs = append(errs, sink.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+// close flushes the pipeline and stops its workers. It returns the sinks that
+// received entries so the caller can close them.
+func (a *asyncWriter) close(ctx context.Context) ([]Sink, error) {
+	err := a.flush(ctx)
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil, err
+	}
+	a.closed = true
+	a.notEmpty.Broadcast()
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+	close(a.stop)
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, errors.Join(err, fmt.Errorf("close %s log: %w", a.stream, ctx.Err()))
+	}
+
+	errs := []error{err}
+	a.mu.Lock()
+	sinks := append([]Sink(nil), a.seen...)
+	if a.spill != nil {
+		errs = append(errs, a.spill.Close())
+		a.spill = nil
+	}
+	a.mu.Unlock()
+	return sinks, errors.Join(errs...)
+}
+
+func containsSink(sinks []Sink, sink Sink) bool {
+	t := reflect.TypeOf(sink)
+	for _, s := range sinks {
+		if reflect.TypeOf(s) != t {
+			continue
+		}
+		if t.Comparable() {
+			if s == sink {
+				return true
+			}
+		} else if reflect.DeepEqual(s, sink) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendSinks appends the sinks not already in the list.
+func appendSinks(list []Sink, sinks ...Sink) []Sink {
+	for _, sink := range sinks {
+		if !containsSink(list, sink) {
+			list = append(list, sink)
+		}
+	}
+	return list
+}
+
+func runningAsyncWriters() []*asyncWriter {
+	asyncWriters.Lock()
+	defer asyncWriters.Unlock()
+	writers := make([]*asyncWriter, 0, len(asyncWriters.m))
+	for _, a := range asyncWriters.m {
+		writers = append(writers, a)
+	}
+	return writers
+}
+
+func flushAsync(ctx context.Context) error {
+	var errs []error
+	for _, a := range runningAsyncWriters() {
+		errs = append(errs, a.flush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// closeAsync closes the pipelines started for conf and returns the sinks
+// that received their entries.
+func closeAsync(ctx context.Context, conf *AsyncConfig) ([]Sink, error) {
+	if conf == nil {
+		return nil, nil
+	}
+
+	var writers []*asyncWriter
+	asyncWriters.Lock()
+	for key, a := range asyncWriters.m {
+		if key.conf == conf {
+			writers = append(writers, a)
+			delete(asyncWriters.m, key)
+		}
+	}
+	asyncWriters.Unlock()
+
+	var sinks []Sink
+	var errs []error
+	for _, a := range writers {
+		seen, err := a.close(ctx)
+		sinks = appendSinks(sinks, seen...)
+		errs = append(errs, err)
+	}
+	return sinks, errors.Join(errs...)
+}
+
+// shutdownAsync closes every running pipeline. Entries written afterwards
+// are written synchronously.
+func shutdownAsync(ctx context.Context) ([]Sink, error) {
+	asyncWriters.Lock()
+	writers := asyncWriters.m
+	asyncWriters.m = map[asyncKey]*asyncWriter{}
+	asyncWriters.Unlock()
+
+	var sinks []Sink
+	var errs []error
+	for _, a := range writers {
+		seen, err := a.close(ctx)
+		sinks = appendSinks(sinks, seen...)
+		errs = append(errs, err)
+	}
+	return sinks, errors.Join(errs...)
+}
+
+// Stats returns the written, dropped and spilled counters of the detail and
+// summary streams along with the number of entries waiting to be written.
+// The counters are process-wide: they add up the entries of every Manager.
+func Stats() map[string]StreamStats {
+	stats := make(map[string]StreamStats, len(streamCounters))
+	for stream, c := range streamCounters {
+		stats[stream] = StreamStats{
+			Written: c.written.Load(),
+			Dropped: c.dropped.Load(),
+			Spilled: c.spilled.Load(),
+		}
+	}
+
+	for _, a := range runningAsyncWriters() {
+		a.mu.Lock()
+		s := stats[a.stream]
+		s.Queued += a.count + a.inflight
+		stats[a.stream] = s
+		a.mu.Unlock()
+	}
+	return stats
+}
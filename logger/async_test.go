@@ -0,0 +1,291 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// gateSink blocks every write until the gate is opened.
+type gateSink struct {
+	MemorySink
+	gate    chan struct{}
+	started chan struct{}
+	once    sync.Once
+}
+
+func newGateSink() *gateSink {
+	return &gateSink{gate: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (s *gateSink) Write(entry Entry) error {
+	s.once.Do(func() { close(s.started) })
+	<-s.gate
+	return s.MemorySink.Write(entry)
+}
+
+func newAsyncTestWriter(t *testing.T, conf AsyncConfig) *asyncWriter {
+	t.Helper()
+	a := newAsyncWriter(StreamDetail, "test_project", conf.withDefaults())
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		a.close(ctx)
+	})
+	return a
+}
+
+func entryOf(payload string) Entry {
+	return Entry{Stream: StreamDetail, Time: time.Now(), Payload: []byte(payload)}
+}
+
+func payloadsOf(entries []Entry) []string {
+	var payloads []string
+	for _, e := range entries {
+		payloads = append(payloads, string(e.Payload))
+	}
+	return payloads
+}
+
+func TestAsyncDetailAndSummary(t *testing.T) {
+	detailSink := NewMemorySink()
+	summarySink := NewMemorySink()
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		Async: &AsyncConfig{
+			BatchSize:     10,
+			FlushInterval: time.Hour,
+		},
+		Sinks: Sinks{
+			Detail:  []Sink{detailSink},
+			Summary: []Sink{summarySink},
+		},
+	}
+	defer Shutdown(context.Background())
+
+	for i := 0; i < 25; i++ {
+		dl := NewDetailLog("test_session", "test_invoke", "test_scenario")
+		dl.AddInputRequest("test_node", "test_cmd", "test_invoke", nil, nil)
+		dl.End()
+
+		sl := NewSummaryLog("test_session", "test_invoke", "test_cmd")
+		sl.End("200", "OK")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := Flush(ctx); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	assert.Len(t, detailSink.Entries(), 25)
+	assert.Len(t, summarySink.Entries(), 25)
+	assert.Equal(t, 0, Stats()[StreamDetail].Queued)
+}
+
+func TestAsyncSeenSinks(t *testing.T) {
+	custom := NewMemorySink()
+	file := zap.NewNop()
+	a := newAsyncTestWriter(t, AsyncConfig{BatchSize: 10, FlushInterval: time.Hour})
+
+	for i := 0; i < 50; i++ {
+		dispatch(a, streamSinks(false, true, file, []Sink{custom}), entryOf("entry"))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := a.flush(ctx); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	assert.Len(t, a.seen, 2, "the file sink of every entry is the same sink")
+}
+
+func TestStatsQueuedAcrossPipelines(t *testing.T) {
+	defer shutdownAsync(context.Background())
+
+	var gates []*gateSink
+	for i := 0; i < 2; i++ {
+		sink := newGateSink()
+		gates = append(gates, sink)
+		a := asyncWriterFor(StreamSummary, "test_project", &AsyncConfig{BatchSize: 1, FlushInterval: time.Hour})
+
+		// one entry blocked in the worker and one buffered
+		dispatch(a, []Sink{sink}, entryOf("1"))
+		<-sink.started
+		dispatch(a, []Sink{sink}, entryOf("2"))
+	}
+
+	assert.Equal(t, 4, Stats()[StreamSummary].Queued)
+	for _, sink := range gates {
+		close(sink.gate)
+	}
+}
+
+func TestAsyncFlushInterval(t *testing.T) {
+	sink := NewMemorySink()
+	a := newAsyncTestWriter(t, AsyncConfig{BatchSize: 10, FlushInterval: 10 * time.Millisecond})
+
+	dispatch(a, []Sink{sink}, entryOf("1"))
+
+	assert.Eventually(t, func() bool {
+		return len(sink.Entries()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestAsyncOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   OverflowPolicy
+		expected []string
+	}{
+		{
+			name:     "Drop newest",
+			policy:   OverflowDropNewest,
+			expected: []string{"1", "2", "3"},
+		},
+		{
+			name:     "Drop oldest",
+			policy:   OverflowDropOldest,
+			expected: []string{"1", "4", "5"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := newGateSink()
+			a := newAsyncTestWriter(t, AsyncConfig{BufferSize: 2, BatchSize: 1, FlushInterval: time.Hour, Policy: tc.policy})
+
+			// the first entry is taken by the worker, which then blocks on the gate
+			dispatch(a, []Sink{sink}, entryOf("1"))
+			<-sink.started
+
+			dropped := a.counter.dropped.Load()
+			for _, p := range []string{"2", "3", "4", "5"} {
+				dispatch(a, []Sink{sink}, entryOf(p))
+			}
+			assert.Equal(t, int64(2), a.counter.dropped.Load()-dropped)
+
+			close(sink.gate)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			assert.NoError(t, a.flush(ctx))
+			assert.Equal(t, tc.expected, payloadsOf(sink.Entries()))
+		})
+	}
+}
+
+func TestAsyncOverflowBlock(t *testing.T) {
+	sink := newGateSink()
+	a := newAsyncTestWriter(t, AsyncConfig{BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour, Policy: OverflowBlock})
+
+	dispatch(a, []Sink{sink}, entryOf("1"))
+	<-sink.started
+	dispatch(a, []Sink{sink}, entryOf("2"))
+
+	done := make(chan struct{})
+	go func() {
+		dispatch(a, []Sink{sink}, entryOf("3"))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Expected dispatch to block while the buffer is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(sink.gate)
+	<-done
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, a.flush(ctx))
+	assert.Equal(t, []string{"1", "2", "3"}, payloadsOf(sink.Entries()))
+}
+
+func TestAsyncOverflowSpill(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+	}
+	dir := t.TempDir()
+	sink := newGateSink()
+	a := newAsyncTestWriter(t, AsyncConfig{BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour, Policy: OverflowSpill, SpillDir: dir})
+
+	dispatch(a, []Sink{sink}, entryOf("1"))
+	<-sink.started
+	dispatch(a, []Sink{sink}, entryOf("2"))
+	dispatch(a, []Sink{sink}, entryOf(`{"spilled":true}`))
+	close(sink.gate)
+
+	b, err := os.ReadFile(filepath.Join(dir, "test_project_detail_spill.log"))
+	if err != nil {
+		t.Fatalf("Expected spill file, but got %v", err)
+	}
+	assert.Equal(t, `{"spilled":true}`, strings.TrimSpace(string(b)))
+}
+
+func TestShutdownWritesSynchronously(t *testing.T) {
+	sink := NewMemorySink()
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		Async:       &AsyncConfig{FlushInterval: time.Hour},
+		Sinks: Sinks{
+			Detail: []Sink{sink},
+		},
+	}
+
+	dl := NewDetailLog("test_session", "test_invoke", "test_scenario")
+	dl.AddInputRequest("test_node", "test_cmd", "test_invoke", nil, nil)
+	dl.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, Shutdown(ctx))
+	assert.Len(t, sink.Entries(), 1)
+
+	// the pipeline the log was created with is closed, so End writes directly
+	dl.AddInputRequest("test_node", "test_cmd", "test_invoke", nil, nil)
+	dl.End()
+	assert.Len(t, sink.Entries(), 2)
+}
+
+func TestReloadClosesReplacedPipelines(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{
+		ProjectName: "test_project",
+		Async:       &AsyncConfig{FlushInterval: time.Hour},
+		Sinks:       Sinks{Detail: []Sink{sink}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer m.Shutdown(context.Background())
+
+	dl := m.NewDetailLog("test_session", "test_invoke", "test_scenario")
+	dl.AddInputRequest("test_node", "test_cmd", "test_invoke", nil, nil)
+	dl.End()
+	replaced := m.Config().Async
+
+	if err := m.Load(LogConfig{Async: &AsyncConfig{BufferSize: 8, FlushInterval: time.Hour}}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	assert.Eventually(t, func() bool {
+		asyncWriters.Lock()
+		defer asyncWriters.Unlock()
+		_, ok := asyncWriters.m[asyncKey{stream: StreamDetail, conf: replaced}]
+		return !ok
+	}, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return len(sink.Entries()) == 1
+	}, time.Second, 5*time.Millisecond, "the replaced pipeline is flushed when it is closed")
+}
@@ -0,0 +1,324 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// TruncatedBody replaces a body larger than DetailLogConfig.MaxBodyBytes, or
+// data cut to fit DetailLogConfig.MaxEntryBytes, in the detail log.
+type TruncatedBody struct {
+	Truncated bool `json:"truncated"`
+	// Length is the original size in bytes, omitted when it is unknown.
+	Length  int64  `json:"length,omitempty"`
+	Preview string `json:"preview"`
+}
+
+func newTruncatedBody(b []byte, limit int, length int64) TruncatedBody {
+	if length < 0 {
+		length = 0
+	}
+	if len(b) > limit {
+		b = b[:limit]
+	}
+	// do not cut a multi-byte character in half
+	for len(b) > 0 && !utf8.Valid(b) {
+		b = b[:len(b)-1]
+	}
+	return TruncatedBody{Truncated: true, Length: length, Preview: string(b)}
+}
+
+// captureBody reads at most limit bytes of body for the log and returns a
+// body that still yields the whole content: the captured bytes followed by
+// the unread rest, so nothing beyond the limit is buffered. A limit of 0
+// reads everything. body is closed when it cannot be read.
+func captureBody(body io.ReadCloser, limit int) (captured []byte, truncated bool, restored io.ReadCloser, err error) {
+	if limit <= 0 {
+		b, err := io.ReadAll(body)
+		body.Close()
+		return b, false, io.NopCloser(bytes.NewReader(b)), err
+	}
+
+	captured, err = io.ReadAll(io.LimitReader(body, int64(limit)+1))
+	if err != nil {
+		body.Close()
+		return captured, false, body, err
+	}
+	restored = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(captured), body), body}
+	if len(captured) > limit {
+		return captured[:limit], true, restored, err
+	}
+	return captured, false, restored, err
+}
+
+// bodyValue returns the value logged for a body: the body decoded according
+// to contentType, or a TruncatedBody (a BinaryBody for binary content) when
+// it was cut at limit. length is the original size, or -1 when unknown.
+func bodyValue(contentType string, b []byte, truncated bool, length int64, limit int) any {
+	if !truncated && (limit <= 0 || len(b) <= limit) {
+		return decodeBody(contentType, b)
+	}
+	if isBinaryContentType(contentType) {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if length < 0 {
+			length = 0
+		}
+		return newBinaryBody(mediaType, b, length, false)
+	}
+	return newTruncatedBody(b, limit, length)
+}
+
+// bodyLimit returns the body limit of d, 0 when unlimited or escalated by
+// EnableDebug.
+func bodyLimit(d DetailLog) int {
+	dl, ok := d.(*detailLog)
+	if !ok {
+		return 0
+	}
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if dl.debug {
+		return 0
+	}
+	return dl.conf.MaxBodyBytes
+}
+
+// fitEntries shrinks the entries of the detail log so that it marshals to
+// at most max bytes: raw data is dropped first, then data is cut to a
+// preview. The result may still exceed max by the size of the envelope.
+func (dl *detailLog) fitEntries(payload []byte, max int) []byte {
+	if len(payload) <= max {
+		return payload
+	}
+
+	marker := fmt.Sprintf("[omitted, detail entry exceeds %d bytes]", max)
+	for _, entries := range [][]InputOutputLog{dl.Input, dl.Output} {
+		for i := range entries {
+			if entries[i].RawData != nil {
+				entries[i].RawData = marker
+			}
+		}
+	}
+	payload, _ = json.Marshal(dl)
+	if len(payload) <= max {
+		return payload
+	}
+
+	count := len(dl.Input) + len(dl.Output)
+	if count == 0 {
+		return payload
+	}
+	budget := max / (2 * count)
+	for _, entries := range [][]InputOutputLog{dl.Input, dl.Output} {
+		for i := range entries {
+			b, _ := json.Marshal(entries[i].Data)
+			if len(b) > budget {
+				entries[i].Data = newTruncatedBody(b, budget, int64(len(b)))
+			}
+		}
+	}
+	payload, _ = json.Marshal(dl)
+	return payload
+}
+
+// MultipartBody is the logged form of a multipart body. File contents are
+// not logged, only their metadata.
+type MultipartBody struct {
+	Fields map[string][]string `json:"fields,omitempty"`
+	Files  []MultipartFile     `json:"files,omitempty"`
+}
+
+// MultipartFile describes a file part of a multipart body.
+type MultipartFile struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+// XMLNode is a generic tree of an XML body.
+type XMLNode struct {
+	Name     string            `json:"name"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Text     string            `json:"text,omitempty"`
+	Children []*XMLNode        `json:"children,omitempty"`
+}
+
+// BinaryBody is the logged form of a binary body: its size, hash and the
+// first bytes in base64. The hash is omitted when the body was truncated.
+type BinaryBody struct {
+	ContentType string `json:"contentType,omitempty"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256,omitempty"`
+	Preview     string `json:"preview,omitempty"`
+}
+
+const binaryPreviewBytes = 64
+
+// decodeBody decodes a body according to its Content-Type: JSON (the
+// default when no Content-Type is set), form values, multipart fields and
+// file metadata, an XML tree, text or a binary summary. A body that does not
+// match its Content-Type is logged as text.
+func decodeBody(contentType string, b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || contentType == "" {
+		return parseBody(b)
+	}
+
+	switch {
+	case mediaType == ContentTypeJSON || strings.HasSuffix(mediaType, "+json"):
+		return parseBody(b)
+	case mediaType == ContentTypeForm:
+		if values, err := url.ParseQuery(string(b)); err == nil {
+			return values
+		}
+	case strings.HasPrefix(mediaType, "multipart/"):
+		if body, err := decodeMultipart(b, params["boundary"]); err == nil {
+			return body
+		}
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		if node, err := decodeXML(b); err == nil {
+			return node
+		}
+	case isTextMediaType(mediaType):
+		return string(b)
+	default:
+		return newBinaryBody(mediaType, b, int64(len(b)), true)
+	}
+	return string(b)
+}
+
+func isTextMediaType(mediaType string) bool {
+	switch mediaType {
+	case "application/javascript", "application/x-ndjson", "application/yaml", "application/graphql":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "text/")
+}
+
+func isBinaryContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == ContentTypeJSON || mediaType == ContentTypeForm || isTextMediaType(mediaType) {
+		return false
+	}
+	return !strings.HasPrefix(mediaType, "multipart/") &&
+		!strings.HasSuffix(mediaType, "+json") &&
+		!strings.HasSuffix(mediaType, "xml")
+}
+
+func newBinaryBody(mediaType string, b []byte, size int64, complete bool) BinaryBody {
+	body := BinaryBody{ContentType: mediaType, Size: size}
+	if complete {
+		sum := sha256.Sum256(b)
+		body.SHA256 = hex.EncodeToString(sum[:])
+	}
+	if len(b) > binaryPreviewBytes {
+		b = b[:binaryPreviewBytes]
+	}
+	body.Preview = base64.StdEncoding.EncodeToString(b)
+	return body
+}
+
+func decodeMultipart(b []byte, boundary string) (MultipartBody, error) {
+	var body MultipartBody
+	if boundary == "" {
+		return body, errors.New("multipart: missing boundary")
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(b), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return body, nil
+		}
+		if err != nil {
+			// keep what was decoded from a truncated body
+			if len(body.Fields) > 0 || len(body.Files) > 0 {
+				return body, nil
+			}
+			return body, err
+		}
+
+		if part.FileName() != "" {
+			size, _ := io.Copy(io.Discard, part)
+			body.Files = append(body.Files, MultipartFile{
+				Field:       part.FormName(),
+				Filename:    part.FileName(),
+				ContentType: part.Header.Get(ContentType),
+				Size:        size,
+			})
+			continue
+		}
+
+		value, _ := io.ReadAll(part)
+		if body.Fields == nil {
+			body.Fields = map[string][]string{}
+		}
+		body.Fields[part.FormName()] = append(body.Fields[part.FormName()], string(value))
+	}
+}
+
+func decodeXML(b []byte) (*XMLNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(b))
+	var root *XMLNode
+	var stack []*XMLNode
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &XMLNode{Name: t.Name.Local}
+			for _, attr := range t.Attr {
+				if node.Attrs == nil {
+					node.Attrs = map[string]string{}
+				}
+				node.Attrs[attr.Name.Local] = attr.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += strings.TrimSpace(string(t))
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("xml: no root element")
+	}
+	return root, nil
+}
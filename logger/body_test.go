@@ -0,0 +1,397 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		limit     int
+		captured  string
+		truncated bool
+	}{
+		{
+			name:     "No limit",
+			body:     "hello world",
+			limit:    0,
+			captured: "hello world",
+		},
+		{
+			name:     "Within limit",
+			body:     "hello",
+			limit:    5,
+			captured: "hello",
+		},
+		{
+			name:      "Over limit",
+			body:      "hello world",
+			limit:     5,
+			captured:  "hello",
+			truncated: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			captured, truncated, body, err := captureBody(io.NopCloser(strings.NewReader(tc.body)), tc.limit)
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			assert.Equal(t, tc.captured, string(captured))
+			assert.Equal(t, tc.truncated, truncated)
+
+			rest, _ := io.ReadAll(body)
+			assert.Equal(t, tc.body, string(rest), "the whole body stays readable")
+		})
+	}
+}
+
+// failingBody fails every read and records Close.
+type failingBody struct {
+	closed bool
+}
+
+func (b *failingBody) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func (b *failingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestCaptureBodyClosesOnError(t *testing.T) {
+	for _, limit := range []int{0, 8} {
+		body := &failingBody{}
+		_, _, _, err := captureBody(body, limit)
+		assert.Error(t, err)
+		assert.True(t, body.closed, "limit %d", limit)
+	}
+}
+
+func TestNewTruncatedBody(t *testing.T) {
+	body := newTruncatedBody([]byte("สวัสดี"), 4, 18)
+	assert.Equal(t, TruncatedBody{Truncated: true, Length: 18, Preview: "ส"}, body)
+}
+
+func detailOutput(t *testing.T, sink *MemorySink) map[string]interface{} {
+	t.Helper()
+	entries := sink.Entries()
+	if len(entries) == 0 {
+		t.Fatal("Expected a detail entry, but got none")
+	}
+	var line map[string]interface{}
+	if err := json.Unmarshal(entries[len(entries)-1].Payload, &line); err != nil {
+		t.Fatalf("Failed to decode detail entry: %v", err)
+	}
+	return line
+}
+
+func TestMiddlewareMaxBodyBytes(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{
+		Detail: DetailLogConfig{MaxBodyBytes: 8},
+		Debug:  DebugConfig{Header: "X-Log-Debug"},
+		Sinks:  Sinks{Detail: []Sink{sink}},
+	}, WithDetailRawData(false))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	reqBody := `{"name":"john","address":"somewhere far away"}`
+	resBody := strings.Repeat("x", 100)
+	handler := Middleware("create_user", WithManager(m))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, reqBody, string(body))
+		w.Write([]byte(resBody[:60]))
+		w.Write([]byte(resBody[60:]))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(reqBody)))
+	assert.Equal(t, resBody, rec.Body.String())
+
+	line := detailOutput(t, sink)
+	input := line["Input"].([]interface{})[0].(map[string]interface{})["Data"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"truncated": true,
+		"length":    float64(len(reqBody)),
+		"preview":   `{"name":`,
+	}, input["body"])
+
+	output := line["Output"].([]interface{})[0].(map[string]interface{})["Data"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"truncated": true,
+		"length":    float64(100),
+		"preview":   "xxxxxxxx",
+	}, output["body"])
+
+	// debug escalation bypasses the limit
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(reqBody))
+	req.Header.Set("X-Log-Debug", "true")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line = detailOutput(t, sink)
+	output = line["Output"].([]interface{})[0].(map[string]interface{})["Data"].(map[string]interface{})
+	assert.Equal(t, resBody, output["body"])
+}
+
+func TestMaxBodyBytesWithMaskFields(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{
+		Detail: DetailLogConfig{
+			MaxBodyBytes: 40,
+			Mask:         &MaskConfig{Fields: []FieldRule{{Path: "password"}}},
+		},
+		Sinks: Sinks{Detail: []Sink{sink}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	reqBody := `{"password":"hunter2secret","name":"john","address":"somewhere far away"}`
+	handler := Middleware("login", WithManager(m))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(reqBody)))
+
+	assert.NotContains(t, string(sink.Entries()[0].Payload), "hunter2")
+	line := detailOutput(t, sink)
+	input := line["Input"].([]interface{})[0].(map[string]interface{})["Data"].(map[string]interface{})
+	body := input["body"].(map[string]interface{})
+	assert.Equal(t, true, body["truncated"])
+	assert.Equal(t, float64(len(reqBody)), body["length"])
+}
+
+func TestDetailLogMaxEntryBytes(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{
+		Detail: DetailLogConfig{MaxEntryBytes: 1024},
+		Sinks:  Sinks{Detail: []Sink{sink}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	small := map[string]string{"id": "1"}
+	dl := m.NewDetailLog("session", "invoke", "scenario")
+	dl.AddInputRequest("client", "cmd", "invoke", small, small)
+	dl.End()
+	assert.Contains(t, string(sink.Entries()[0].Payload), `"RawData"`)
+	assert.NotContains(t, string(sink.Entries()[0].Payload), "omitted")
+
+	large := map[string]string{"data": strings.Repeat("x", 4096)}
+	dl = m.NewDetailLog("session", "invoke", "scenario")
+	dl.AddInputRequest("client", "cmd", "invoke", large, large)
+	dl.AddOutputResponse("client", "cmd", "invoke", small, small)
+	dl.End()
+
+	payload := sink.Entries()[1].Payload
+	assert.LessOrEqual(t, len(payload), 1024)
+
+	line := detailOutput(t, sink)
+	input := line["Input"].([]interface{})[0].(map[string]interface{})
+	assert.Contains(t, input["RawData"], "omitted")
+	assert.Equal(t, true, input["Data"].(map[string]interface{})["truncated"])
+
+	output := line["Output"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"id": "1"}, output["Data"])
+}
+
+func TestTransportMaxBodyBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("y", 50)))
+	}))
+	defer server.Close()
+
+	sink := NewMemorySink()
+	m, err := New(LogConfig{
+		Detail: DetailLogConfig{MaxBodyBytes: 10},
+		Sinks:  Sinks{Detail: []Sink{sink}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	dl := m.NewDetailLog("session", "invoke", "scenario")
+	req, _ := http.NewRequestWithContext(WithDetailLog(context.Background(), dl), http.MethodGet, server.URL, nil)
+	resp, err := (&http.Client{Transport: NewTransport(nil, "api", "get")}).Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, 50, len(body))
+	dl.End()
+
+	line := detailOutput(t, sink)
+	input := line["Input"].([]interface{})[0].(map[string]interface{})["Data"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"truncated": true,
+		"length":    float64(50),
+		"preview":   "yyyyyyyyyy",
+	}, input["body"])
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransportRequestMaxBodyBytes(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{
+		Detail: DetailLogConfig{MaxBodyBytes: 10},
+		Sinks:  Sinks{Detail: []Sink{sink}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	payload := strings.Repeat("z", 1000)
+	reader := &countingReader{Reader: strings.NewReader(payload)}
+	var readBefore int
+	var sent []byte
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		readBefore = reader.n
+		sent, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+
+	dl := m.NewDetailLog("session", "invoke", "scenario")
+	req, _ := http.NewRequestWithContext(WithDetailLog(context.Background(), dl), http.MethodPost, "http://upload", io.NopCloser(reader))
+	req.ContentLength = int64(len(payload))
+	if _, err := (&http.Client{Transport: NewTransport(base, "api", "upload")}).Do(req); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	dl.End()
+
+	assert.LessOrEqual(t, readBefore, 11, "only the logged part is buffered")
+	assert.Equal(t, payload, string(sent))
+
+	line := detailOutput(t, sink)
+	output := line["Output"].([]interface{})[0].(map[string]interface{})["Data"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"truncated": true,
+		"length":    float64(1000),
+		"preview":   "zzzzzzzzzz",
+	}, output["body"])
+}
+
+func TestDecodeBody(t *testing.T) {
+	multipartBody := "--b\r\n" +
+		"Content-Disposition: form-data; name=\"name\"\r\n\r\n" +
+		"john\r\n" +
+		"--b\r\n" +
+		"Content-Disposition: form-data; name=\"avatar\"; filename=\"me.png\"\r\n" +
+		"Content-Type: image/png\r\n\r\n" +
+		"\x89PNG....\r\n" +
+		"--b--\r\n"
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    any
+	}{
+		{
+			name:        "JSON",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name":"john"}`,
+			expected:    map[string]interface{}{"name": "john"},
+		},
+		{
+			name:     "No Content-Type",
+			body:     `{"name":"john"}`,
+			expected: map[string]interface{}{"name": "john"},
+		},
+		{
+			name:        "Form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=john&tag=a&tag=b",
+			expected:    url.Values{"name": {"john"}, "tag": {"a", "b"}},
+		},
+		{
+			name:        "Multipart",
+			contentType: "multipart/form-data; boundary=b",
+			body:        multipartBody,
+			expected: MultipartBody{
+				Fields: map[string][]string{"name": {"john"}},
+				Files:  []MultipartFile{{Field: "avatar", Filename: "me.png", ContentType: "image/png", Size: 8}},
+			},
+		},
+		{
+			name:        "XML",
+			contentType: "application/xml",
+			body:        `<user id="1"><name>john</name><tag>a</tag></user>`,
+			expected: &XMLNode{
+				Name:  "user",
+				Attrs: map[string]string{"id": "1"},
+				Children: []*XMLNode{
+					{Name: "name", Text: "john"},
+					{Name: "tag", Text: "a"},
+				},
+			},
+		},
+		{
+			name:        "Invalid XML",
+			contentType: "text/xml",
+			body:        "<user>",
+			expected:    "<user>",
+		},
+		{
+			name:        "Text",
+			contentType: "text/plain",
+			body:        "hello",
+			expected:    "hello",
+		},
+		{
+			name:        "Binary",
+			contentType: "application/octet-stream",
+			body:        "\x00\x01\x02",
+			expected: BinaryBody{
+				ContentType: "application/octet-stream",
+				Size:        3,
+				SHA256:      "ae4b3280e56e2faf83f414a6e3dabe9d5fbe18976544c05fed121accb85b53fc",
+				Preview:     "AAEC",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, decodeBody(tc.contentType, []byte(tc.body)))
+		})
+	}
+}
+
+func TestBodyValueTruncatedBinary(t *testing.T) {
+	body := strings.Repeat("\x00", 100)
+	value := bodyValue("image/png", []byte(body[:10]), true, 100, 10)
+	assert.Equal(t, BinaryBody{ContentType: "image/png", Size: 100, Preview: "AAAAAAAAAAAAAA=="}, value)
+}
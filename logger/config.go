@@ -0,0 +1,372 @@
+package logger
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// explicitField marks the options of a LogConfig that were set explicitly,
+// so that a false or zero value still overrides the current configuration.
+type explicitField uint16
+
+const (
+	explicitAppLogFile explicitField = 1 << iota
+	explicitAppLogConsole
+	explicitAppLogLevel
+	explicitDetailRawData
+	explicitDetailLogFile
+	explicitDetailLogConsole
+	explicitSummaryRawData
+	explicitSummaryLogFile
+	explicitSummaryLogConsole
+)
+
+// explicitKeys maps the config keys, as "section.key", to their field.
+var explicitKeys = map[string]explicitField{
+	"appLog.logFile":     explicitAppLogFile,
+	"appLog.logConsole":  explicitAppLogConsole,
+	"appLog.logLevel":    explicitAppLogLevel,
+	"detail.rawData":     explicitDetailRawData,
+	"detail.logFile":     explicitDetailLogFile,
+	"detail.logConsole":  explicitDetailLogConsole,
+	"summary.rawData":    explicitSummaryRawData,
+	"summary.logFile":    explicitSummaryLogFile,
+	"summary.logConsole": explicitSummaryLogConsole,
+}
+
+// ConfigOption sets a single option of a LogConfig, including to false or
+// to the zero level, which a plain LogConfig cannot express.
+type ConfigOption func(*LogConfig)
+
+// WithAppLogFile turns writing the app log to a file on or off.
+func WithAppLogFile(on bool) ConfigOption {
+	return func(c *LogConfig) {
+		c.AppLog.LogFile = on
+		c.explicit |= explicitAppLogFile
+	}
+}
+
+// WithAppLogConsole turns writing the app log to stdout on or off.
+func WithAppLogConsole(on bool) ConfigOption {
+	return func(c *LogConfig) {
+		c.AppLog.LogConsole = on
+		c.explicit |= explicitAppLogConsole
+	}
+}
+
+// WithAppLogLevel sets the app log level, including zapcore.InfoLevel.
+func WithAppLogLevel(level zapcore.Level) ConfigOption {
+	return func(c *LogConfig) {
+		c.AppLog.LogLevel = level
+		c.explicit |= explicitAppLogLevel
+	}
+}
+
+// WithDetailRawData turns the raw data of the detail log on or off.
+func WithDetailRawData(on bool) ConfigOption {
+	return func(c *LogConfig) {
+		c.Detail.RawData = on
+		c.explicit |= explicitDetailRawData
+	}
+}
+
+// WithDetailLogFile turns writing the detail log to a file on or off.
+func WithDetailLogFile(on bool) ConfigOption {
+	return func(c *LogConfig) {
+		c.Detail.LogFile = on
+		c.explicit |= explicitDetailLogFile
+	}
+}
+
+// WithDetailLogConsole turns writing the detail log to stdout on or off.
+func WithDetailLogConsole(on bool) ConfigOption {
+	return func(c *LogConfig) {
+		c.Detail.LogConsole = on
+		c.explicit |= explicitDetailLogConsole
+	}
+}
+
+// WithSummaryRawData turns the raw data of the summary log on or off.
+func WithSummaryRawData(on bool) ConfigOption {
+	return func(c *LogConfig) {
+		c.Summary.RawData = on
+		c.explicit |= explicitSummaryRawData
+	}
+}
+
+// WithSummaryLogFile turns writing the summary log to a file on or off.
+func WithSummaryLogFile(on bool) ConfigOption {
+	return func(c *LogConfig) {
+		c.Summary.LogFile = on
+		c.explicit |= explicitSummaryLogFile
+	}
+}
+
+// WithSummaryLogConsole turns writing the summary log to stdout on or off.
+func WithSummaryLogConsole(on bool) ConfigOption {
+	return func(c *LogConfig) {
+		c.Summary.LogConsole = on
+		c.explicit |= explicitSummaryLogConsole
+	}
+}
+
+// With returns a copy of c with opts applied.
+func (c LogConfig) With(opts ...ConfigOption) LogConfig {
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// ConfigFromFile reads a LogConfig from a JSON or YAML file. Files ending in
+// .yaml or .yml are read as YAML, anything else as JSON.
+func ConfigFromFile(path string) (LogConfig, error) {
+	var cfg LogConfig
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("read log config: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var data interface{}
+		if err := yaml.Unmarshal(b, &data); err != nil {
+			return cfg, fmt.Errorf("parse log config %s: %w", path, err)
+		}
+		if data == nil {
+			return cfg, nil
+		}
+		if b, err = json.Marshal(data); err != nil {
+			return cfg, fmt.Errorf("parse log config %s: %w", path, err)
+		}
+	}
+
+	cfg, err = decodeLogConfig(b)
+	if err != nil {
+		return cfg, fmt.Errorf("parse log config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// decodeLogConfig decodes a JSON config, rejecting unknown keys. The options
+// present in b are set explicitly, so a false value is applied when merged.
+func decodeLogConfig(b []byte) (LogConfig, error) {
+	var cfg LogConfig
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, err
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(b, &sections); err != nil {
+		return cfg, err
+	}
+	for name, raw := range sections {
+		var keys map[string]json.RawMessage
+		if json.Unmarshal(raw, &keys) != nil {
+			continue
+		}
+		for key := range keys {
+			cfg.markExplicit(func(section, field string) bool {
+				return strings.EqualFold(section, name) && strings.EqualFold(field, key)
+			})
+		}
+	}
+	return cfg, nil
+}
+
+// ConfigFromEnv reads a LogConfig from environment variables named after the
+// json tags of the config, e.g. with the prefix "LOG":
+//
+//	LOG_PROJECTNAME=order-service
+//	LOG_APPLOG_LOGLEVEL=info
+//	LOG_DETAIL_LOGFILE=true
+//	LOG_DETAIL_MASK_HEADERS=Authorization,Cookie
+//	LOG_ASYNC_FLUSHINTERVAL=2s
+func ConfigFromEnv(prefix string) (LogConfig, error) {
+	var cfg LogConfig
+	prefix = strings.TrimSuffix(strings.ToUpper(prefix), "_")
+	_, err := envStruct(reflect.ValueOf(&cfg).Elem(), prefix)
+	cfg.markExplicit(func(section, field string) bool {
+		_, ok := os.LookupEnv(envKey(prefix, section, field))
+		return ok
+	})
+	return cfg, err
+}
+
+// markExplicit flags the fields of explicitKeys for which present is true.
+func (c *LogConfig) markExplicit(present func(section, field string) bool) {
+	for key, bit := range explicitKeys {
+		section, field, _ := strings.Cut(key, ".")
+		if present(section, field) {
+			c.explicit |= bit
+		}
+	}
+}
+
+func envKey(prefix string, names ...string) string {
+	key := strings.ToUpper(strings.Join(names, "_"))
+	if prefix != "" {
+		key = prefix + "_" + key
+	}
+	return key
+}
+
+// LoadLogConfigFrom loads the configuration with the precedence
+// defaults < file < env < code: path (skipped when empty) is read first, then
+// the environment variables with envPrefix and finally code and opts are
+// applied on top.
+func LoadLogConfigFrom(path, envPrefix string, code LogConfig, opts ...ConfigOption) (*LogConfig, error) {
+	cfg, err := fileAndEnvConfig(path, envPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if err := defaultManager.load(cfg, code.With(opts...)); err != nil {
+		return nil, err
+	}
+	conf := defaultManager.Config()
+	return &conf, nil
+}
+
+// fileAndEnvConfig merges the config file at path (skipped when empty) and
+// the environment variables with envPrefix.
+func fileAndEnvConfig(path, envPrefix string) (LogConfig, error) {
+	var cfg LogConfig
+	if path != "" {
+		fileCfg, err := ConfigFromFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		mergeLogConfig(&cfg, fileCfg)
+	}
+
+	envCfg, err := ConfigFromEnv(envPrefix)
+	if err != nil {
+		return cfg, err
+	}
+	mergeLogConfig(&cfg, envCfg)
+	return cfg, nil
+}
+
+// String returns the configuration as indented JSON.
+func (c LogConfig) String() string {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%#v", c)
+	}
+	return string(b)
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// envStruct fills the fields of v from the environment and reports whether
+// any variable was found.
+func envStruct(v reflect.Value, prefix string) (bool, error) {
+	var errs []error
+	found := false
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		ok, err := envField(v.Field(i), envKey(prefix, name))
+		found = found || ok
+		errs = append(errs, err)
+	}
+	return found, errors.Join(errs...)
+}
+
+func envField(f reflect.Value, key string) (bool, error) {
+	if f.CanAddr() && f.Addr().Type().Implements(textUnmarshalerType) {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			return false, nil
+		}
+		if err := f.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return true, fmt.Errorf("%s: %w", key, err)
+		}
+		return true, nil
+	}
+
+	switch f.Kind() {
+	case reflect.Struct:
+		return envStruct(f, key)
+	case reflect.Pointer:
+		if f.Type().Elem().Kind() != reflect.Struct {
+			return false, nil
+		}
+		v := reflect.New(f.Type().Elem())
+		found, err := envStruct(v.Elem(), key)
+		if found {
+			f.Set(v)
+		}
+		return found, err
+	}
+
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return false, nil
+	}
+
+	switch {
+	case f.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("%s: %w", key, err)
+		}
+		f.SetInt(int64(d))
+	case f.Kind() == reflect.String:
+		f.SetString(value)
+	case f.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, fmt.Errorf("%s: %w", key, err)
+		}
+		f.SetBool(b)
+	case f.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return true, fmt.Errorf("%s: %w", key, err)
+		}
+		f.SetInt(int64(n))
+	case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		list := reflect.MakeSlice(f.Type(), len(items), len(items))
+		for i, item := range items {
+			list.Index(i).SetString(item)
+		}
+		f.Set(list)
+	default:
+		return true, fmt.Errorf("%s: not supported in environment variables, use a config file", key)
+	}
+	return true, nil
+}
@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestConfigFromFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "JSON",
+			file: "log.json",
+			content: `{
+				"projectName": "order-service",
+				"appLog": {"logLevel": "warn", "logConsole": true},
+				"detail": {"rawData": true, "mask": {"headers": ["Authorization"]}},
+				"async": {"bufferSize": 16, "flushInterval": "250ms"}
+			}`,
+		},
+		{
+			name: "YAML",
+			file: "log.yaml",
+			content: `
+projectName: order-service
+appLog:
+  logLevel: warn
+  logConsole: true
+detail:
+  rawData: true
+  mask:
+    headers: [Authorization]
+async:
+  bufferSize: 16
+  flushInterval: 250ms
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+
+			cfg, err := ConfigFromFile(path)
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+
+			assert.Equal(t, "order-service", cfg.ProjectName)
+			assert.Equal(t, zapcore.WarnLevel, cfg.AppLog.LogLevel)
+			assert.True(t, cfg.AppLog.LogConsole)
+			assert.True(t, cfg.Detail.RawData)
+			assert.Equal(t, []string{"Authorization"}, cfg.Detail.Mask.Headers)
+			assert.Equal(t, 16, cfg.Async.BufferSize)
+			assert.Equal(t, 250*time.Millisecond, cfg.Async.FlushInterval)
+		})
+	}
+}
+
+func TestConfigFromFileUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.yml")
+	if err := os.WriteFile(path, []byte("detail:\n  logFiel: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	_, err := ConfigFromFile(path)
+	if err == nil || !strings.Contains(err.Error(), "logFiel") {
+		t.Errorf("Expected unknown field error, but got %v", err)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("APP_LOG_PROJECTNAME", "order-service")
+	t.Setenv("APP_LOG_APPLOG_LOGLEVEL", "error")
+	t.Setenv("APP_LOG_DETAIL_LOGCONSOLE", "true")
+	t.Setenv("APP_LOG_SUMMARY_MASK_HEADERS", "Authorization, Cookie")
+	t.Setenv("APP_LOG_ASYNC_FLUSHINTERVAL", "2s")
+
+	cfg, err := ConfigFromEnv("APP_LOG_")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	assert.Equal(t, "order-service", cfg.ProjectName)
+	assert.Equal(t, zapcore.ErrorLevel, cfg.AppLog.LogLevel)
+	assert.True(t, cfg.Detail.LogConsole)
+	assert.Nil(t, cfg.Detail.Mask)
+	assert.Equal(t, []string{"Authorization", "Cookie"}, cfg.Summary.Mask.Headers)
+	assert.Equal(t, 2*time.Second, cfg.Async.FlushInterval)
+
+	t.Setenv("APP_LOG_DETAIL_RAWDATA", "maybe")
+	t.Setenv("APP_LOG_DETAIL_MASK_FIELDS", "password")
+	_, err = ConfigFromEnv("APP_LOG")
+	for _, expected := range []string{"APP_LOG_DETAIL_RAWDATA", "APP_LOG_DETAIL_MASK_FIELDS"} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to mention %s, but got %v", expected, err)
+		}
+	}
+}
+
+func TestLoadLogConfigFrom(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "default_project",
+		Namespace:   "default_namespace",
+		AppLog: AppLog{
+			Name: "./logs/app",
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "log.json")
+	content := `{"projectName": "file_project", "namespace": "file_namespace", "detail": {"name": "./logs/file"}, "summary": {"name": "./logs/file_summary"}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	t.Setenv("LOG_NAMESPACE", "env_namespace")
+	t.Setenv("LOG_DETAIL_NAME", "./logs/env")
+
+	cfg, err := LoadLogConfigFrom(path, "LOG", LogConfig{
+		Detail: DetailLogConfig{Name: "./logs/code"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	assert.Equal(t, "./logs/app", cfg.AppLog.Name, "default")
+	assert.Equal(t, "file_project", cfg.ProjectName, "file")
+	assert.Equal(t, "./logs/file_summary", cfg.Summary.Name, "file")
+	assert.Equal(t, "env_namespace", cfg.Namespace, "env")
+	assert.Equal(t, "./logs/code", cfg.Detail.Name, "code")
+
+	var printed map[string]interface{}
+	if err := json.Unmarshal([]byte(cfg.String()), &printed); err != nil {
+		t.Fatalf("Expected the effective config as JSON, but got %v", err)
+	}
+	assert.Equal(t, "env_namespace", printed["namespace"])
+}
+
+func TestLoadLogConfigExplicitFalse(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		AppLog:      AppLog{Name: "./logs/app", LogConsole: true, LogLevel: zapcore.WarnLevel},
+		Detail:      DetailLogConfig{Name: "./logs/detail", RawData: true},
+		Summary:     SummaryLogConfig{Name: "./logs/summary", RawData: true},
+	}
+
+	// a plain false keeps the current value
+	cfg := LoadLogConfig(LogConfig{Detail: DetailLogConfig{RawData: false}})
+	assert.True(t, cfg.Detail.RawData)
+
+	cfg = LoadLogConfig(LogConfig{},
+		WithDetailRawData(false),
+		WithSummaryRawData(false),
+		WithAppLogConsole(false),
+		WithAppLogLevel(zapcore.InfoLevel),
+	)
+	assert.False(t, cfg.Detail.RawData)
+	assert.False(t, cfg.Summary.RawData)
+	assert.False(t, cfg.AppLog.LogConsole)
+	assert.Equal(t, zapcore.InfoLevel, cfg.AppLog.LogLevel)
+}
+
+func TestLoadLogConfigFromExplicitFalse(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		AppLog:      AppLog{Name: "./logs/app", LogConsole: true},
+		Detail:      DetailLogConfig{Name: "./logs/detail", RawData: true},
+		Summary:     SummaryLogConfig{Name: "./logs/summary", RawData: true},
+	}
+
+	path := filepath.Join(t.TempDir(), "log.yaml")
+	if err := os.WriteFile(path, []byte("detail:\n  rawData: false\nsummary:\n  rawData: false\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	t.Setenv("LOG_APPLOG_LOGCONSOLE", "false")
+	t.Setenv("LOG_SUMMARY_RAWDATA", "true")
+
+	cfg, err := LoadLogConfigFrom(path, "LOG", LogConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	assert.False(t, cfg.Detail.RawData, "file")
+	assert.True(t, cfg.Summary.RawData, "env overrides file")
+	assert.False(t, cfg.AppLog.LogConsole, "env")
+
+	cfg, err = LoadLogConfigFrom(path, "LOG", LogConfig{}, WithSummaryRawData(false))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	assert.False(t, cfg.Summary.RawData, "code overrides env")
+}
+
+func TestLoadLogConfigReturnsCopy(t *testing.T) {
+	configLog = LogConfig{ProjectName: "test_project"}
+	cfg, err := LoadLogConfigE(LogConfig{ProjectName: "first"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	LoadLogConfig(LogConfig{ProjectName: "second"})
+	assert.Equal(t, "first", cfg.ProjectName)
+}
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithDetailLog returns a copy of ctx carrying the given DetailLog.
+func WithDetailLog(ctx context.Context, detailLog DetailLog) context.Context {
+	return context.WithValue(ctx, detailLogKey, detailLog)
+}
+
+// DetailLogFromContext returns the DetailLog stored in ctx, or a no-op
+// DetailLog when there is none.
+func DetailLogFromContext(ctx context.Context) DetailLog {
+	if detailLog, ok := ctx.Value(detailLogKey).(DetailLog); ok && detailLog != nil {
+		return detailLog
+	}
+	return noopDetailLog{}
+}
+
+// WithSummaryLog returns a copy of ctx carrying the given SummaryLog.
+func WithSummaryLog(ctx context.Context, summaryLog SummaryLog) context.Context {
+	return context.WithValue(ctx, summaryLogKey, summaryLog)
+}
+
+// SummaryLogFromContext returns the SummaryLog stored in ctx, or a no-op
+// SummaryLog when there is none.
+func SummaryLogFromContext(ctx context.Context) SummaryLog {
+	if summaryLog, ok := ctx.Value(summaryLogKey).(SummaryLog); ok && summaryLog != nil {
+		return summaryLog
+	}
+	return noopSummaryLog{}
+}
+
+type noopDetailLog struct{}
+
+func (noopDetailLog) IsRawDataEnabled() bool { return false }
+
+func (noopDetailLog) AddInputRequest(node, cmd, invoke string, rawData, data interface{}) {}
+
+func (noopDetailLog) AddInputHttpRequest(node, cmd, invoke string, req *http.Request, rawData bool) {}
+
+func (noopDetailLog) AddOutputRequest(node, cmd, invoke string, rawData, data interface{}) {}
+
+func (noopDetailLog) End() {}
+
+func (noopDetailLog) AddInputResponse(node, cmd, invoke string, rawData, data interface{}, protocol, protocolMethod string) {
+}
+
+func (noopDetailLog) AddOutputResponse(node, cmd, invoke string, rawData, data interface{}) {}
+
+func (noopDetailLog) AutoEnd() bool { return false }
+
+type noopSummaryLog struct{}
+
+func (noopSummaryLog) AddField(fieldName string, fieldValue interface{}) {}
+
+func (noopSummaryLog) AddSuccess(node, cmd, code, desc string) {}
+
+func (noopSummaryLog) AddError(node, cmd, code, desc string) {}
+
+func (noopSummaryLog) IsEnd() bool { return true }
+
+func (noopSummaryLog) End(resultCode, resultDescription string) error { return nil }
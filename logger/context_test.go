@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetailLogFromContext(t *testing.T) {
+	// Test case 1: DetailLog exists in context
+	configLog = LogConfig{
+		ProjectName: "test_project",
+	}
+	detailLog := NewDetailLog("test_session", "test_invoke", "test_scenario")
+	ctx := WithDetailLog(context.Background(), detailLog)
+	assert.Same(t, detailLog, DetailLogFromContext(ctx))
+
+	// Test case 2: DetailLog does not exist in context
+	noop := DetailLogFromContext(context.Background())
+	if noop == nil {
+		t.Fatal("Expected a no-op DetailLog to be returned, but got nil")
+	}
+	noop.AddInputRequest("node", "cmd", "invoke", nil, nil)
+	noop.AddOutputRequest("node", "cmd", "invoke", nil, nil)
+	noop.End()
+	assert.False(t, noop.IsRawDataEnabled())
+	assert.False(t, noop.AutoEnd())
+}
+
+func TestSummaryLogFromContext(t *testing.T) {
+	// Test case 1: SummaryLog exists in context
+	configLog = LogConfig{
+		ProjectName: "test_project",
+	}
+	summaryLog := NewSummaryLog("test_session", "test_invoke", "test_cmd")
+	ctx := WithSummaryLog(context.Background(), summaryLog)
+	assert.Same(t, summaryLog, SummaryLogFromContext(ctx))
+
+	// Test case 2: SummaryLog does not exist in context
+	noop := SummaryLogFromContext(context.Background())
+	if noop == nil {
+		t.Fatal("Expected a no-op SummaryLog to be returned, but got nil")
+	}
+	noop.AddField("field", "value")
+	noop.AddSuccess("node", "cmd", "200", "OK")
+	assert.True(t, noop.IsEnd())
+	assert.NoError(t, noop.End("200", "OK"))
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DebugConfig sets the request header that escalates a single transaction
+// to debug logging: its DetailLog records raw data and full bodies and its
+// app logger runs at debug level. The header is ignored while Header is
+// empty. With a Secret the header must carry a token made by DebugToken,
+// otherwise any of "1", "true" or "on" triggers it.
+type DebugConfig struct {
+	Header string `json:"header,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// hiddenSecret replaces the secret when the configuration is printed. It is
+// ignored when merged back, so a printed configuration can be loaded again.
+const hiddenSecret = "******"
+
+// MarshalJSON hides the secret when the configuration is printed.
+func (c DebugConfig) MarshalJSON() ([]byte, error) {
+	type debugConfig DebugConfig
+	if c.Secret != "" {
+		c.Secret = hiddenSecret
+	}
+	return json.Marshal(debugConfig(c))
+}
+
+const debugKey ContextKey = "log_debug"
+
+// WithDebug flags the transaction of ctx for debug logging, as the debug
+// header does. It has to be set before the logs are created, e.g. in a
+// middleware in front of Middleware.
+func WithDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugKey, true)
+}
+
+// IsDebug reports whether the transaction of ctx is flagged for debug logging.
+func IsDebug(ctx context.Context) bool {
+	on, _ := ctx.Value(debugKey).(bool)
+	return on
+}
+
+// DebugToken returns a debug header value signed with secret and valid for ttl.
+func DebugToken(secret string, ttl time.Duration) string {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	return expires + "." + debugSignature(secret, expires)
+}
+
+func debugSignature(secret, expires string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// requested reports whether r carries a valid debug header.
+func (c DebugConfig) requested(r *http.Request) bool {
+	if c.Header == "" {
+		return false
+	}
+	value := strings.TrimSpace(r.Header.Get(c.Header))
+	if value == "" {
+		return false
+	}
+
+	if c.Secret == "" {
+		switch strings.ToLower(value) {
+		case "1", "true", "on":
+			return true
+		}
+		return false
+	}
+
+	expires, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(debugSignature(c.Secret, expires)))
+}
+
+// EnableDebug escalates a DetailLog to debug logging: raw data is recorded
+// from now on and bodies are not truncated.
+func EnableDebug(d DetailLog) {
+	if dl, ok := d.(*detailLog); ok {
+		dl.mu.Lock()
+		dl.debug = true
+		dl.conf.RawData = true
+		dl.mu.Unlock()
+	}
+}
+
+// DebugLogger returns logger logging at debug level whatever the level of
+// its Manager. Loggers not created by a Manager are returned unchanged.
+func DebugLogger(logger *zap.Logger) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if c, ok := core.(appCore); ok {
+			c.debug = true
+			return c
+		}
+		return core
+	}))
+}
@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDebugConfigRequested(t *testing.T) {
+	secret := "s3cret"
+	tests := []struct {
+		name     string
+		conf     DebugConfig
+		value    string
+		expected bool
+	}{
+		{
+			name:     "Header not configured",
+			conf:     DebugConfig{},
+			value:    "true",
+			expected: false,
+		},
+		{
+			name:     "Plain flag",
+			conf:     DebugConfig{Header: "X-Log-Debug"},
+			value:    "true",
+			expected: true,
+		},
+		{
+			name:     "Plain flag off",
+			conf:     DebugConfig{Header: "X-Log-Debug"},
+			value:    "false",
+			expected: false,
+		},
+		{
+			name:     "Signed token",
+			conf:     DebugConfig{Header: "X-Log-Debug", Secret: secret},
+			value:    DebugToken(secret, time.Minute),
+			expected: true,
+		},
+		{
+			name:     "Plain flag with secret",
+			conf:     DebugConfig{Header: "X-Log-Debug", Secret: secret},
+			value:    "true",
+			expected: false,
+		},
+		{
+			name:     "Token signed with another secret",
+			conf:     DebugConfig{Header: "X-Log-Debug", Secret: secret},
+			value:    DebugToken("other", time.Minute),
+			expected: false,
+		},
+		{
+			name:     "Expired token",
+			conf:     DebugConfig{Header: "X-Log-Debug", Secret: secret},
+			value:    DebugToken(secret, -time.Minute),
+			expected: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Log-Debug", tc.value)
+			assert.Equal(t, tc.expected, tc.conf.requested(req))
+		})
+	}
+}
+
+func TestMiddlewareDebugHeader(t *testing.T) {
+	sink := NewMemorySink()
+	appSink := NewMemorySink()
+	m, err := New(LogConfig{
+		ProjectName: "test_project",
+		Debug:       DebugConfig{Header: "X-Log-Debug"},
+		Sinks:       Sinks{App: []Sink{appSink}, Detail: []Sink{sink}},
+	}, WithDetailRawData(false), WithAppLogLevel(zapcore.WarnLevel))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	handler := Middleware("create_user", WithManager(m), WithLogger(m.NewLogger()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NewLog(r.Context()).Debug("debug message")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	captureStdout(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"john"}`)))
+
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"jane"}`))
+		req.Header.Set("X-Log-Debug", "1")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	})
+
+	entries := sink.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 detail entries, but got %d", len(entries))
+	}
+	assert.NotContains(t, string(entries[0].Payload), "RawData")
+	assert.Contains(t, string(entries[1].Payload), "RawData")
+
+	// only the escalated request logged at debug level
+	assert.Len(t, appSink.Entries(), 1)
+	assert.Contains(t, string(appSink.Entries()[0].Payload), "debug message")
+}
+
+func TestInitSessionWithDebug(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{Sinks: Sinks{App: []Sink{sink}}}, WithAppLogLevel(zapcore.ErrorLevel))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	base := m.NewLogger()
+
+	captureStdout(t, func() {
+		_, l := m.InitSession(context.Background(), base)
+		l.Debug("normal")
+
+		ctx := WithDebug(context.Background())
+		assert.True(t, IsDebug(ctx))
+		_, l = m.InitSession(ctx, base)
+		l.Debug("escalated")
+		base.Debug("base")
+	})
+
+	assert.Len(t, sink.Entries(), 1)
+	assert.Contains(t, string(sink.Entries()[0].Payload), "escalated")
+}
+
+func TestDebugConfigHidesSecret(t *testing.T) {
+	cfg := LogConfig{Debug: DebugConfig{Header: "X-Log-Debug", Secret: "s3cret"}}
+	assert.NotContains(t, cfg.String(), "s3cret")
+	assert.Contains(t, cfg.String(), "X-Log-Debug")
+}
+
+func TestAdminConfigKeepsSecret(t *testing.T) {
+	m, err := New(LogConfig{Debug: DebugConfig{Header: "X-Log-Debug", Secret: "s3cret"}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	h := http.StripPrefix("/debug/logger", m.AdminHandler())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/logger/config", nil))
+	code, _ := adminRequest(t, h, http.MethodPut, "/config", rec.Body.String())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "s3cret", m.Config().Debug.Secret)
+}
+
+func TestDebugLoggerWithLogFile(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{
+		ProjectName: "test_project",
+		AppLog:      AppLog{Name: t.TempDir(), LogFile: true},
+		Sinks:       Sinks{App: []Sink{sink}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	// the file defaults to info, debug lines need an escalation
+	assert.False(t, m.NewLogger().Core().Enabled(zapcore.DebugLevel))
+	m.NewLogger().Debug("quiet")
+	DebugLogger(m.NewLogger()).Debug("escalated")
+	if assert.Len(t, sink.Entries(), 1) {
+		assert.Contains(t, string(sink.Entries()[0].Payload), "escalated")
+	}
+
+	m.Load(LogConfig{}, WithAppLogLevel(zapcore.DebugLevel))
+	assert.True(t, m.NewLogger().Core().Enabled(zapcore.DebugLevel))
+}
@@ -1,10 +1,8 @@
 package logger
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
@@ -25,31 +23,42 @@ type DetailLog interface {
 }
 
 func NewDetailLog(Session, initInvoke, scenario string) DetailLog {
+	return defaultManager.NewDetailLog(Session, initInvoke, scenario)
+}
+
+// NewDetailLog creates a detail log with the configuration of the Manager.
+func (m *Manager) NewDetailLog(Session, initInvoke, scenario string) DetailLog {
 	// session := req.Context().Value(xSession)
+	conf := m.config()
 	currentTime := time.Now()
 	if Session == "" {
 		Session = fmt.Sprintf("default_%s", currentTime.Format("20060102150405"))
 	}
 
 	if initInvoke == "" {
-		initInvoke = fmt.Sprintf("%s_%s", configLog.ProjectName, currentTime.Format("20060102150405"))
+		initInvoke = fmt.Sprintf("%s_%s", conf.ProjectName, currentTime.Format("20060102150405"))
 	}
 	host, _ := os.Hostname()
 	data := &detailLog{
 		LogType:       Detail,
 		Host:          host,
-		AppName:       configLog.ProjectName,
+		AppName:       conf.ProjectName,
 		Instance:      getInstance(),
 		Session:       Session,
 		InitInvoke:    initInvoke,
 		Scenario:      scenario,
 		Input:         []InputOutputLog{},
 		Output:        []InputOutputLog{},
-		conf:          configLog.Detail,
+		conf:          conf.Detail,
+		sinks:         conf.Sinks.Detail,
+		async:         asyncWriterFor(StreamDetail, conf.ProjectName, conf.Async),
+		streams:       m.streams,
+		open:          &m.open,
 		startTimeDate: time.Now(),
 		timeCounter:   make(map[string]time.Time),
 		// req:           req,
 	}
+	data.open.trackDetail(data)
 
 	return data
 }
@@ -64,9 +73,20 @@ func (dl *detailLog) IsRawDataEnabled() bool {
 }
 
 type InComing struct {
-	Header any        `json:"header,omitempty"`
-	Query  url.Values `json:"query,omitempty"`
-	Body   any        `json:"body,omitempty"`
+	StatusCode int        `json:"statusCode,omitempty"`
+	Header     any        `json:"header,omitempty"`
+	Query      url.Values `json:"query,omitempty"`
+	Body       any        `json:"body,omitempty"`
+}
+
+type OutGoing struct {
+	Method     string `json:"method,omitempty"`
+	URL        string `json:"url,omitempty"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Header     any    `json:"header,omitempty"`
+	Body       any    `json:"body,omitempty"`
+	// Size is the number of body bytes of a response written by the handler.
+	Size *int64 `json:"size,omitempty"`
 }
 
 func (dl *detailLog) AddInputHttpRequest(node, cmd, invoke string, req *http.Request, rawData bool) {
@@ -76,10 +96,12 @@ func (dl *detailLog) AddInputHttpRequest(node, cmd, invoke string, req *http.Req
 		Body:   nil,
 	}
 
-	bodyBytes, _ := io.ReadAll(req.Body)
-
-	req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-	json.Unmarshal(bodyBytes, &data.Body)
+	if req.Body != nil && req.Body != http.NoBody {
+		limit := bodyLimit(dl)
+		bodyBytes, truncated, body, _ := captureBody(req.Body, limit)
+		req.Body = body
+		data.Body = bodyValue(req.Header.Get(ContentType), bodyBytes, truncated, req.ContentLength, limit)
+	}
 
 	var raw string
 	if rawData {
@@ -138,6 +160,62 @@ func (dl *detailLog) AddInputResponse(node, cmd, invoke string, rawData, data in
 	})
 }
 
+// AddInputHttpResponse records on d the response to an outbound request:
+// status, headers and the body decoded by its Content-Type, leaving
+// resp.Body readable. Protocol and method are taken from resp.Request. For
+// the detail logs of this package ResTime is the time elapsed since
+// AddOutputRequest was called with the same invoke, other DetailLogs get the
+// response through AddInputResponse.
+func AddInputHttpResponse(d DetailLog, node, cmd, invoke string, resp *http.Response) {
+	if _, ok := d.(noopDetailLog); ok {
+		return
+	}
+
+	data := InComing{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
+
+	if resp.Body != nil && resp.Body != http.NoBody {
+		limit := bodyLimit(d)
+		bodyBytes, truncated, body, _ := captureBody(resp.Body, limit)
+		resp.Body = body
+		data.Body = bodyValue(resp.Header.Get(ContentType), bodyBytes, truncated, resp.ContentLength, limit)
+	}
+
+	var raw interface{}
+	if d.IsRawDataEnabled() {
+		raw = data
+	}
+
+	protocol, protocolMethod := resp.Proto, ""
+	if req := resp.Request; req != nil {
+		protocolMethod = req.Method
+		if req.Proto != "" {
+			protocol = req.Proto
+		}
+	}
+
+	dl, ok := d.(*detailLog)
+	if !ok {
+		d.AddInputResponse(node, cmd, invoke, raw, data, protocol, protocolMethod)
+		return
+	}
+	if raw != nil {
+		raw = ToJson(raw)
+	}
+	dl.addInput(&logEvent{
+		node:           node,
+		cmd:            cmd,
+		invoke:         invoke,
+		logType:        "res",
+		rawData:        raw,
+		data:           data,
+		protocol:       protocol,
+		protocolMethod: protocolMethod,
+	})
+}
+
 func (dl *detailLog) AddOutputResponse(node, cmd, invoke string, rawData, data interface{}) {
 	if rawData != nil {
 		if _, ok := rawData.(string); !ok {
@@ -162,6 +240,7 @@ func (dl *detailLog) addInput(input *logEvent) {
 	now := time.Now()
 	if dl.startTimeDate.IsZero() {
 		dl.startTimeDate = now
+		dl.open.trackDetail(dl)
 	}
 
 	var resTimeString string
@@ -210,8 +289,15 @@ func (dl *detailLog) AddOutput(out logEvent) {
 	dl.mu.Lock()
 	defer dl.mu.Unlock()
 	now := time.Now()
+	if dl.startTimeDate.IsZero() {
+		dl.startTimeDate = now
+		dl.open.trackDetail(dl)
+	}
 	if out.invoke != "" && out.logType != "res" {
-		dl.timeCounter[out.invoke] = now
+		if out.start.IsZero() {
+			out.start = now
+		}
+		dl.timeCounter[out.invoke] = out.start
 	}
 
 	protocolValue := dl.buildValueProtocol(&out.protocol, &out.protocolMethod)
@@ -226,10 +312,60 @@ func (dl *detailLog) AddOutput(out logEvent) {
 	dl.Output = append(dl.Output, outputLog)
 }
 
+// addInputEvent and addOutputEvent record an entry with its protocol for
+// the helpers of other transports, falling back to the DetailLog methods for
+// implementations other than the one of the package.
+func addInputEvent(d DetailLog, e logEvent) {
+	dl, ok := d.(*detailLog)
+	if !ok {
+		if e.logType == "res" {
+			d.AddInputResponse(e.node, e.cmd, e.invoke, e.data, e.data, e.protocol, e.protocolMethod)
+		} else {
+			d.AddInputRequest(e.node, e.cmd, e.invoke, e.data, e.data)
+		}
+		return
+	}
+	if dl.IsRawDataEnabled() {
+		e.rawData = ToJson(e.data)
+	}
+	e.data = ToStruct(e.data)
+	dl.addInput(&e)
+}
+
+func addOutputEvent(d DetailLog, e logEvent) {
+	dl, ok := d.(*detailLog)
+	if !ok {
+		if e.logType == "res" {
+			d.AddOutputResponse(e.node, e.cmd, e.invoke, e.data, e.data)
+		} else {
+			d.AddOutputRequest(e.node, e.cmd, e.invoke, e.data, e.data)
+		}
+		return
+	}
+	if dl.IsRawDataEnabled() {
+		e.rawData = ToJson(e.data)
+	}
+	e.data = ToStruct(e.data)
+	dl.AddOutput(e)
+}
+
 func (dl *detailLog) End() {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
 	if dl.startTimeDate.IsZero() {
+		if dl.forceEnded {
+			// already written by Shutdown
+			dl.forceEnded = false
+			return
+		}
 		log.Fatal("end() called without any input/output")
 	}
+	dl.end()
+}
+
+// end writes the log and resets it, dl.mu must be held.
+func (dl *detailLog) end() {
+	dl.forceEnded = false
 
 	processingTime := fmt.Sprintf("%d ms", time.Since(dl.startTimeDate).Milliseconds())
 	dl.ProcessingTime = &processingTime
@@ -240,17 +376,23 @@ func (dl *detailLog) End() {
 	outputTimeStamp := dl.formatTime(dl.outputTime)
 	dl.OutputTimeStamp = outputTimeStamp
 
-	logDetail, _ := json.Marshal(dl)
-	if dl.conf.LogConsole {
-		os.Stdout.Write(logDetail)
-		os.Stdout.Write([]byte(endOfLine()))
-	}
+	dl.conf.Mask.applyEntries(dl.Input)
+	dl.conf.Mask.applyEntries(dl.Output)
 
-	if dl.conf.LogFile && dl.conf.LogDetail != nil {
-		dl.conf.LogDetail.Info(string(logDetail))
+	if !dl.streams.disabled(StreamDetail) {
+		logDetail, _ := json.Marshal(dl)
+		if max := dl.conf.MaxEntryBytes; max > 0 && !dl.debug {
+			logDetail = dl.fitEntries(logDetail, max)
+		}
+		dispatch(dl.async, streamSinks(dl.conf.LogConsole, dl.conf.LogFile, dl.conf.LogDetail, dl.sinks), Entry{
+			Stream:  StreamDetail,
+			Time:    time.Now(),
+			Payload: logDetail,
+		})
 	}
 
 	dl.clear()
+	dl.open.untrackDetail(dl)
 }
 
 func (dl *detailLog) buildValueProtocol(protocol, method *string) *string {
@@ -265,6 +407,22 @@ func (dl *detailLog) buildValueProtocol(protocol, method *string) *string {
 }
 
 func (dl *detailLog) AutoEnd() bool {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	return dl.autoEnd()
+}
+
+// forceEnd ends the log on behalf of its handler, which may still call End
+// afterwards.
+func (dl *detailLog) forceEnd() {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if dl.autoEnd() {
+		dl.forceEnded = true
+	}
+}
+
+func (dl *detailLog) autoEnd() bool {
 	if dl.startTimeDate.IsZero() {
 		return false
 	}
@@ -272,7 +430,7 @@ func (dl *detailLog) AutoEnd() bool {
 		return false
 	}
 
-	dl.End()
+	dl.end()
 	return true
 }
 
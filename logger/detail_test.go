@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -441,6 +444,82 @@ func TestAddInputResponse(t *testing.T) {
 		})
 	}
 }
+func TestAddInputHttpResponse(t *testing.T) {
+	m, err := New(LogConfig{ProjectName: "test_project"}, WithDetailRawData(true))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	dl := m.NewDetailLog("test_session", "test_invoke", "test_scenario").(*detailLog)
+	req := httptest.NewRequest(http.MethodPost, "http://api/users", nil)
+	dl.AddOutputRequest("api", "create_user", "invoke_1", nil, nil)
+	time.Sleep(5 * time.Millisecond)
+
+	resp := &http.Response{
+		StatusCode:    http.StatusCreated,
+		Proto:         "HTTP/2.0",
+		Header:        http.Header{ContentType: {ContentTypeJSON}},
+		Body:          io.NopCloser(strings.NewReader(`{"id":1}`)),
+		ContentLength: 8,
+		Request:       req,
+	}
+	AddInputHttpResponse(dl, "api", "create_user", "invoke_1", resp)
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"id":1}`, string(body), "the body stays readable")
+
+	if len(dl.Input) != 1 {
+		t.Fatalf("Expected 1 input log, but got %d", len(dl.Input))
+	}
+	input := dl.Input[0]
+	assert.Equal(t, "res", input.Type)
+	assert.Equal(t, "api.create_user", input.Event)
+	assert.Equal(t, "HTTP/1.1.POST", *input.Protocol)
+	assert.Equal(t, InComing{
+		StatusCode: http.StatusCreated,
+		Header:     resp.Header,
+		Body:       map[string]interface{}{"id": float64(1)},
+	}, input.Data)
+	assert.NotNil(t, input.RawData)
+
+	elapsed, err := strconv.Atoi(strings.TrimSuffix(*input.ResTime, " ms"))
+	if err != nil {
+		t.Fatalf("Expected ResTime to be a duration, but got %q", *input.ResTime)
+	}
+	assert.GreaterOrEqual(t, elapsed, 5)
+}
+
+func TestAddInputResponseKeepsResTime(t *testing.T) {
+	m, err := New(LogConfig{ProjectName: "test_project"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	dl := m.NewDetailLog("test_session", "test_invoke", "test_scenario").(*detailLog)
+	dl.AddOutputRequest("api", "create_user", "invoke_1", nil, nil)
+	dl.AddInputResponse("api", "create_user", "invoke_1", nil, nil, "HTTP/1.1", http.MethodPost)
+
+	_, err = time.Parse(time.RFC3339, *dl.Input[0].ResTime)
+	assert.NoError(t, err, "the elapsed time only replaces ResTime for AddInputHttpResponse")
+}
+
+func TestAddInputHttpResponseMock(t *testing.T) {
+	mockLog := new(MockDetailLog)
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{ContentType: {ContentTypeJSON}},
+		Body:       io.NopCloser(strings.NewReader(`{"id":1}`)),
+		Request:    httptest.NewRequest(http.MethodGet, "http://api/users/1", nil),
+	}
+	data := InComing{StatusCode: http.StatusOK, Header: resp.Header, Body: map[string]interface{}{"id": float64(1)}}
+
+	mockLog.On("IsRawDataEnabled").Return(false)
+	mockLog.On("AddInputResponse", "api", "get_user", "invoke_1", nil, data, "HTTP/1.1", http.MethodGet).Return()
+	AddInputHttpResponse(mockLog, "api", "get_user", "invoke_1", resp)
+	mockLog.AssertExpectations(t)
+}
+
 func TestAddOutputResponse(t *testing.T) {
 	tests := []struct {
 		name      string
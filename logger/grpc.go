@@ -0,0 +1,406 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// GRPCProto is the protocol of the gRPC entries in the detail log.
+	GRPCProto = "grpc"
+	// SessionMetadata and XTidMetadata are the metadata keys carrying the
+	// session and the invoke of the caller to the next service.
+	SessionMetadata = "session"
+	XTidMetadata    = "x-tid"
+)
+
+// GRPCData is the data of a gRPC entry in the detail log.
+type GRPCData struct {
+	Metadata metadata.MD `json:"metadata,omitempty"`
+	Message  any         `json:"message,omitempty"`
+	Code     string      `json:"code,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// UnaryServerInterceptor creates a DetailLog and a SummaryLog for every call,
+// as Middleware does for HTTP. The session and invoke are continued from the
+// incoming metadata when present. The scenario is the full method name and
+// the cmd defaults to the method name. WithSessionHeader sets the metadata key
+// of the session.
+func UnaryServerInterceptor(opts ...MiddlewareOption) grpc.UnaryServerInterceptor {
+	cfg := newGRPCConfig(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, call := cfg.begin(ctx, info.FullMethod)
+		defer call.recoverPanic()
+
+		call.input(req)
+		resp, err := handler(ctx, req)
+		call.output(resp, err)
+		call.end(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of
+// UnaryServerInterceptor: every message received and sent is recorded.
+func StreamServerInterceptor(opts ...MiddlewareOption) grpc.StreamServerInterceptor {
+	cfg := newGRPCConfig(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, call := cfg.begin(ss.Context(), info.FullMethod)
+		defer call.recoverPanic()
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx, call: call})
+		if err != nil {
+			call.output(nil, err)
+		}
+		call.end(err)
+		return err
+	}
+}
+
+func newGRPCConfig(opts []MiddlewareOption) middlewareConfig {
+	cfg := middlewareConfig{
+		node:          "client",
+		sessionHeader: SessionMetadata,
+		manager:       defaultManager,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+type grpcServerCall struct {
+	node       string
+	cmd        string
+	method     string
+	invoke     string
+	metadata   metadata.MD
+	detailLog  DetailLog
+	summaryLog SummaryLog
+}
+
+func (cfg middlewareConfig) begin(ctx context.Context, fullMethod string) (context.Context, *grpcServerCall) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	session := firstMetadata(md, cfg.sessionHeader)
+	if session == "" {
+		session, _ = ctx.Value(xSession).(string)
+	}
+	if session == "" {
+		session = newSessionID()
+	}
+	ctx = context.WithValue(ctx, xSession, session)
+	ctx = WithTraceContext(ctx, extractTraceContext(func(key string) string {
+		return firstMetadata(md, key)
+	}))
+	if cfg.logger != nil {
+		ctx, _ = cfg.manager.InitSession(ctx, cfg.logger)
+	}
+
+	invoke := firstMetadata(md, XTidMetadata)
+	if invoke == "" {
+		invoke = GenerateXTid(cfg.node)
+	}
+	cmd := cfg.cmd
+	if cmd == "" {
+		cmd = grpcMethodName(fullMethod)
+	}
+
+	call := &grpcServerCall{
+		node:       cfg.node,
+		cmd:        cmd,
+		method:     fullMethod,
+		invoke:     invoke,
+		metadata:   md,
+		detailLog:  cfg.manager.NewDetailLog(session, invoke, fullMethod),
+		summaryLog: cfg.manager.NewSummaryLog(session, invoke, fullMethod),
+	}
+	if IsDebug(ctx) {
+		EnableDebug(call.detailLog)
+	}
+	traceLogs(ctx, call.detailLog, call.summaryLog)
+	ctx = WithDetailLog(ctx, call.detailLog)
+	ctx = WithSummaryLog(ctx, call.summaryLog)
+	return ctx, call
+}
+
+func (c *grpcServerCall) event(logType string, data GRPCData) logEvent {
+	return logEvent{node: c.node, cmd: c.cmd, invoke: c.invoke, logType: logType, data: data, protocol: GRPCProto, protocolMethod: c.method}
+}
+
+func (c *grpcServerCall) input(msg any) {
+	data := GRPCData{Metadata: c.metadata, Message: grpcMessage(msg)}
+	// the metadata is logged with the first message only
+	c.metadata = nil
+	addInputEvent(c.detailLog, c.event("req", data))
+}
+
+func (c *grpcServerCall) output(msg any, err error) {
+	data := GRPCData{Message: grpcMessage(msg)}
+	if err != nil {
+		st := status.Convert(err)
+		data = GRPCData{Code: st.Code().String(), Error: st.Message()}
+	}
+	addOutputEvent(c.detailLog, c.event("res", data))
+}
+
+func (c *grpcServerCall) end(err error) {
+	code, desc := grpcResult(err)
+	if err != nil {
+		c.summaryLog.AddError(c.node, c.cmd, code, grpcErrorDesc(err))
+	} else {
+		c.summaryLog.AddSuccess(c.node, c.cmd, code, desc)
+	}
+	c.detailLog.AutoEnd()
+	if !c.summaryLog.IsEnd() {
+		c.summaryLog.End(code, desc)
+	}
+}
+
+func (c *grpcServerCall) recoverPanic() {
+	if rec := recover(); rec != nil {
+		err := status.Errorf(codes.Internal, "%v", rec)
+		c.output(nil, err)
+		c.end(err)
+		panic(rec)
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	call *grpcServerCall
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.call.input(m)
+	}
+	return err
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.call.output(m, nil)
+	}
+	return err
+}
+
+// UnaryClientInterceptor records every outbound call on the DetailLog and
+// SummaryLog of the call context, as NewTransport does for HTTP, and passes
+// the session and invoke to the server in the metadata. The cmd is the method
+// name.
+func UnaryClientInterceptor(node string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, call := beginGRPCClient(ctx, node, method)
+
+		call.output(req)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		call.input(reply, err)
+		call.end(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor is the streaming counterpart of
+// UnaryClientInterceptor: every message sent and received is recorded and
+// the call is added to the summary once RecvMsg returns an error or io.EOF,
+// or, for client-streaming calls, once the single response is received.
+func StreamClientInterceptor(node string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, call := beginGRPCClient(ctx, node, method)
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			call.input(nil, err)
+			call.end(err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, call: call, single: !desc.ServerStreams}, nil
+	}
+}
+
+type grpcClientCall struct {
+	node       string
+	cmd        string
+	method     string
+	invoke     string
+	detailLog  DetailLog
+	summaryLog SummaryLog
+}
+
+func beginGRPCClient(ctx context.Context, node, method string) (context.Context, *grpcClientCall) {
+	invoke := GenerateXTid(node)
+	pairs := []string{XTidMetadata, invoke}
+	if session, ok := ctx.Value(xSession).(string); ok && session != "" {
+		pairs = append(pairs, SessionMetadata, session)
+	}
+	injectTraceContext(ctx, func(key, value string) {
+		pairs = append(pairs, key, value)
+	})
+	ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+
+	return ctx, &grpcClientCall{
+		node:       node,
+		cmd:        grpcMethodName(method),
+		method:     method,
+		invoke:     invoke,
+		detailLog:  DetailLogFromContext(ctx),
+		summaryLog: SummaryLogFromContext(ctx),
+	}
+}
+
+func (c *grpcClientCall) event(logType string, data GRPCData) logEvent {
+	return logEvent{node: c.node, cmd: c.cmd, invoke: c.invoke, logType: logType, data: data, protocol: GRPCProto, protocolMethod: c.method}
+}
+
+func (c *grpcClientCall) output(msg any) {
+	addOutputEvent(c.detailLog, c.event("rep", GRPCData{Message: grpcMessage(msg)}))
+}
+
+func (c *grpcClientCall) input(msg any, err error) {
+	data := GRPCData{Message: grpcMessage(msg)}
+	if err != nil {
+		st := status.Convert(err)
+		data = GRPCData{Code: st.Code().String(), Error: st.Message()}
+	}
+	addInputEvent(c.detailLog, c.event("res", data))
+}
+
+func (c *grpcClientCall) end(err error) {
+	code, desc := grpcResult(err)
+	if err != nil {
+		c.summaryLog.AddError(c.node, c.cmd, code, grpcErrorDesc(err))
+	} else {
+		c.summaryLog.AddSuccess(c.node, c.cmd, code, desc)
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	call   *grpcClientCall
+	single bool // the server sends a single response
+	done   bool
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.call.output(m)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.call.input(m, nil)
+		if s.single && !s.done {
+			s.done = true
+			s.call.end(nil)
+		}
+	case s.done:
+	case errors.Is(err, io.EOF):
+		s.done = true
+		s.call.end(nil)
+	default:
+		s.done = true
+		s.call.input(nil, err)
+		s.call.end(err)
+	}
+	return err
+}
+
+// grpcMessage returns the logged form of a message: the protojson form of
+// protobuf messages, the JSON form of anything else.
+func grpcMessage(msg any) any {
+	if msg == nil {
+		return nil
+	}
+	if m, ok := msg.(proto.Message); ok {
+		if b, err := protojson.Marshal(m); err == nil {
+			return parseBody(b)
+		}
+	}
+	return ToStruct(msg)
+}
+
+// grpcResult maps the status of err to the result code of the summary, the
+// HTTP status of the gRPC code, and the name of the gRPC code.
+func grpcResult(err error) (string, string) {
+	code := status.Code(err)
+	return strconv.Itoa(grpcHTTPStatus(code)), code.String()
+}
+
+func grpcErrorDesc(err error) string {
+	if msg := status.Convert(err).Message(); msg != "" {
+		return msg
+	}
+	return status.Code(err).String()
+}
+
+func grpcHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// grpcMethodName returns the method of a full method name such as
+// "/package.Service/Method".
+func grpcMethodName(fullMethod string) string {
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[i+1:]
+	}
+	return fullMethod
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if key == "" {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,242 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testSinks struct {
+	detail  *MemorySink
+	summary *MemorySink
+}
+
+func newSinkTestManager(t *testing.T) (*Manager, testSinks) {
+	t.Helper()
+	sinks := testSinks{detail: NewMemorySink(), summary: NewMemorySink()}
+	m, err := New(LogConfig{
+		ProjectName: "test_project",
+		Sinks:       Sinks{Detail: []Sink{sinks.detail}, Summary: []Sink{sinks.summary}},
+	}, WithDetailRawData(false))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	return m, sinks
+}
+
+func startGRPCTestServer(t *testing.T, m *Manager) healthpb.HealthClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(WithManager(m))),
+		grpc.StreamInterceptor(StreamServerInterceptor(WithManager(m))),
+	)
+	hs := health.NewServer()
+	hs.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, hs)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor("health")),
+		grpc.WithStreamInterceptor(StreamClientInterceptor("health")),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func lastEntry(t *testing.T, sink *MemorySink) map[string]interface{} {
+	t.Helper()
+	assert.Eventually(t, func() bool { return len(sink.Entries()) > 0 }, time.Second, 5*time.Millisecond)
+	return detailOutput(t, sink)
+}
+
+func TestGRPCUnaryInterceptors(t *testing.T) {
+	serverManager, serverSinks := newSinkTestManager(t)
+	clientManager, clientSinks := newSinkTestManager(t)
+	client := startGRPCTestServer(t, serverManager)
+
+	tests := []struct {
+		name     string
+		service  string
+		code     codes.Code
+		result   string
+		desc     string
+		response interface{}
+	}{
+		{
+			name:     "Success",
+			service:  "users",
+			code:     codes.OK,
+			result:   "200",
+			desc:     "OK",
+			response: map[string]interface{}{"status": "SERVING"},
+		},
+		{
+			name:    "Error",
+			service: "orders",
+			code:    codes.NotFound,
+			result:  "404",
+			desc:    "NotFound",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), xSession, "session_"+tc.name)
+			detailLog := clientManager.NewDetailLog("session_"+tc.name, "", "check")
+			summaryLog := clientManager.NewSummaryLog("session_"+tc.name, "", "check")
+			ctx = WithSummaryLog(WithDetailLog(ctx, detailLog), summaryLog)
+
+			_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: tc.service})
+			assert.Equal(t, tc.code, status.Code(err))
+			detailLog.End()
+			summaryLog.End("200", "")
+
+			// the server continues the session and invoke of the client
+			client := detailOutput(t, clientSinks.detail)
+			server := lastEntry(t, serverSinks.detail)
+			assert.Equal(t, "session_"+tc.name, server["Session"])
+			invoke := client["Output"].([]interface{})[0].(map[string]interface{})["Invoke"]
+			assert.Equal(t, invoke, server["InitInvoke"])
+
+			input := server["Input"].([]interface{})[0].(map[string]interface{})
+			assert.Equal(t, "client.Check", input["Event"])
+			assert.Equal(t, "grpc./grpc.health.v1.Health/Check", input["Protocol"])
+			assert.Equal(t, map[string]interface{}{"service": tc.service}, input["Data"].(map[string]interface{})["message"])
+
+			output := client["Input"].([]interface{})[0].(map[string]interface{})
+			assert.Equal(t, "health.Check", output["Event"])
+			assert.Equal(t, "grpc./grpc.health.v1.Health/Check", output["Protocol"])
+			assert.Equal(t, tc.response, output["Data"].(map[string]interface{})["message"])
+
+			summary := lastEntry(t, serverSinks.summary)
+			assert.Equal(t, "/grpc.health.v1.Health/Check", summary["Scenario"])
+			assert.Equal(t, tc.result, summary["ResponseResult"])
+			assert.Equal(t, tc.desc, summary["ResponseDesc"])
+
+			var clientSummary LogSummaryEntry
+			json.Unmarshal(clientSinks.summary.Entries()[len(clientSinks.summary.Entries())-1].Payload, &clientSummary)
+			assert.Equal(t, "health", clientSummary.Sequences[0].Node)
+			assert.Equal(t, "Check", clientSummary.Sequences[0].Cmd)
+			assert.Equal(t, tc.result, clientSummary.Sequences[0].Result[0].ResultCode)
+		})
+	}
+}
+
+func TestGRPCStreamInterceptors(t *testing.T) {
+	serverManager, serverSinks := newSinkTestManager(t)
+	clientManager, clientSinks := newSinkTestManager(t)
+	client := startGRPCTestServer(t, serverManager)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	detailLog := clientManager.NewDetailLog("session", "", "watch")
+	summaryLog := clientManager.NewSummaryLog("session", "", "watch")
+	ctx = WithSummaryLog(WithDetailLog(ctx, detailLog), summaryLog)
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "users"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	detailLog.End()
+	summaryLog.End("200", "")
+
+	line := detailOutput(t, clientSinks.detail)
+	assert.Len(t, line["Output"], 1)
+	assert.Len(t, line["Input"], 2)
+	assert.Contains(t, string(clientSinks.summary.Entries()[0].Payload), `"ResultCode":"499"`)
+
+	server := lastEntry(t, serverSinks.detail)
+	assert.Equal(t, "grpc./grpc.health.v1.Health/Watch", server["Input"].([]interface{})[0].(map[string]interface{})["Protocol"])
+	output := server["Output"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"status": "SERVING"}, output["Data"].(map[string]interface{})["message"])
+	assert.Equal(t, "499", lastEntry(t, serverSinks.summary)["ResponseResult"])
+}
+
+// fakeClientStream answers a client-streaming call with a single response.
+type fakeClientStream struct {
+	grpc.ClientStream
+	sent int
+}
+
+func (s *fakeClientStream) SendMsg(m any) error {
+	s.sent++
+	return nil
+}
+
+func (s *fakeClientStream) CloseSend() error { return nil }
+
+func (s *fakeClientStream) RecvMsg(m any) error {
+	m.(*healthpb.HealthCheckResponse).Status = healthpb.HealthCheckResponse_SERVING
+	return nil
+}
+
+func TestGRPCClientStreamingInterceptor(t *testing.T) {
+	clientManager, clientSinks := newSinkTestManager(t)
+	detailLog := clientManager.NewDetailLog("session", "", "upload")
+	summaryLog := clientManager.NewSummaryLog("session", "", "upload")
+	ctx := WithSummaryLog(WithDetailLog(context.Background(), detailLog), summaryLog)
+
+	desc := &grpc.StreamDesc{StreamName: "Upload", ClientStreams: true}
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{}, nil
+	}
+	stream, err := StreamClientInterceptor("health")(ctx, desc, nil, "/grpc.health.v1.Health/Upload", streamer)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	// CloseAndRecv of the generated client: no io.EOF follows the response
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, stream.SendMsg(&healthpb.HealthCheckRequest{Service: "users"}))
+	}
+	assert.NoError(t, stream.CloseSend())
+	assert.NoError(t, stream.RecvMsg(&healthpb.HealthCheckResponse{}))
+	detailLog.End()
+	summaryLog.End("200", "")
+
+	line := detailOutput(t, clientSinks.detail)
+	assert.Len(t, line["Output"], 2)
+	assert.Len(t, line["Input"], 1)
+
+	var clientSummary LogSummaryEntry
+	json.Unmarshal(clientSinks.summary.Entries()[0].Payload, &clientSummary)
+	if assert.Len(t, clientSummary.Sequences, 1) {
+		assert.Equal(t, "Upload", clientSummary.Sequences[0].Cmd)
+		assert.Equal(t, "200", clientSummary.Sequences[0].Result[0].ResultCode)
+	}
+}
+
+func TestGRPCHTTPStatus(t *testing.T) {
+	assert.Equal(t, 200, grpcHTTPStatus(codes.OK))
+	assert.Equal(t, 401, grpcHTTPStatus(codes.Unauthenticated))
+	assert.Equal(t, 503, grpcHTTPStatus(codes.Unavailable))
+	assert.Equal(t, 500, grpcHTTPStatus(codes.DataLoss))
+}
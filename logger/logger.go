@@ -2,6 +2,7 @@ package logger
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -15,12 +16,22 @@ import (
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+var errProjectNameRequired = errors.New("projectName: required when logFile is enabled, it names the log files")
+
 type LogConfig struct {
-	ProjectName string
-	Namespace   string
+	ProjectName string           `json:"projectName"`
+	Namespace   string           `json:"namespace"`
 	AppLog      AppLog           `json:"appLog"`
 	Summary     SummaryLogConfig `json:"summary"`
 	Detail      DetailLogConfig  `json:"detail"`
+	Async       *AsyncConfig     `json:"async,omitempty"`
+	Shutdown    ShutdownConfig   `json:"shutdown"`
+	Debug       DebugConfig      `json:"debug"`
+	Sinks       Sinks            `json:"-"`
+	// Propagator reads and writes the correlation headers of HTTP calls.
+	// When nil only the W3C trace is propagated.
+	Propagator Propagator `json:"-"`
+	explicit   explicitField
 }
 
 type AppLog struct {
@@ -28,23 +39,31 @@ type AppLog struct {
 	LogFile    bool          `json:"logFile"`
 	LogConsole bool          `json:"logConsole"`
 	LogLevel   zapcore.Level `json:"logLevel"`
-	AppLog     *zap.Logger
+	AppLog     *zap.Logger   `json:"-"`
 }
 
 type SummaryLogConfig struct {
-	Name       string `json:"name"`
-	RawData    bool   `json:"rawData"`
-	LogFile    bool   `json:"logFile"`
-	LogConsole bool   `json:"logConsole"`
-	LogSummary *zap.Logger
+	Name       string      `json:"name"`
+	RawData    bool        `json:"rawData"`
+	LogFile    bool        `json:"logFile"`
+	LogConsole bool        `json:"logConsole"`
+	Mask       *MaskConfig `json:"mask,omitempty"`
+	LogSummary *zap.Logger `json:"-"`
 }
 
 type DetailLogConfig struct {
-	Name       string `json:"name"`
-	RawData    bool   `json:"rawData"`
-	LogFile    bool   `json:"logFile"`
-	LogConsole bool   `json:"logConsole"`
-	LogDetail  *zap.Logger
+	Name       string      `json:"name"`
+	RawData    bool        `json:"rawData"`
+	LogFile    bool        `json:"logFile"`
+	LogConsole bool        `json:"logConsole"`
+	Mask       *MaskConfig `json:"mask,omitempty"`
+	// MaxBodyBytes limits the request and response bodies kept in the
+	// detail log, larger bodies are logged as a TruncatedBody (0: no limit).
+	MaxBodyBytes int `json:"maxBodyBytes,omitempty"`
+	// MaxEntryBytes limits the size of a detail log line by dropping raw
+	// data, then cutting data to a preview (0: no limit).
+	MaxEntryBytes int         `json:"maxEntryBytes,omitempty"`
+	LogDetail     *zap.Logger `json:"-"`
 }
 
 type InputOutputLog struct {
@@ -65,6 +84,8 @@ type detailLog struct {
 	Session         string               `json:"Session"`
 	InitInvoke      string               `json:"InitInvoke"`
 	Scenario        string               `json:"Scenario"`
+	TraceId         string               `json:"TraceId,omitempty"`
+	SpanId          string               `json:"SpanId,omitempty"`
 	Identity        string               `json:"Identity"`
 	InputTimeStamp  *string              `json:"InputTimeStamp,omitempty"`
 	Input           []InputOutputLog     `json:"Input"`
@@ -72,10 +93,17 @@ type detailLog struct {
 	Output          []InputOutputLog     `json:"Output"`
 	ProcessingTime  *string              `json:"ProcessingTime,omitempty"`
 	conf            DetailLogConfig      `json:"-"`
+	sinks           []Sink               `json:"-"`
+	async           *asyncWriter         `json:"-"`
+	streams         *streamSwitches      `json:"-"`
+	debug           bool                 `json:"-"`
 	startTimeDate   time.Time            `json:"-"`
 	inputTime       *time.Time           `json:"-"`
 	outputTime      *time.Time           `json:"-"`
 	timeCounter     map[string]time.Time `json:"-"`
+	open            *openLogSet          `json:"-"`
+	// forceEnded is set when Shutdown ended the log before its handler did
+	forceEnded bool
 	// req             *http.Request
 	mu sync.Mutex
 }
@@ -90,6 +118,9 @@ type logEvent struct {
 	resTime        string
 	protocol       string
 	protocolMethod string
+	// start is the time an output event started when it is recorded
+	// afterwards, the ResTime of its response is measured from it.
+	start time.Time
 }
 
 type summaryLog struct {
@@ -98,9 +129,15 @@ type summaryLog struct {
 	session       string
 	initInvoke    string
 	cmd           string
+	traceID       string
+	spanID        string
 	blockDetail   []BlockDetail
 	optionalField OptionalFields
 	conf          LogConfig
+	async         *asyncWriter
+	streams       *streamSwitches
+	metrics       *summaryMetrics
+	open          *openLogSet
 }
 
 type SummaryResult struct {
@@ -124,8 +161,11 @@ const (
 	TraceIDKey      ContextKey = "trace_id"
 	SpanIDKey       ContextKey = "span_id"
 	xSession        ContextKey = "session"
+	detailLogKey    ContextKey = "detail_log"
+	summaryLogKey   ContextKey = "summary_log"
 	ContentType                = "Content-Type"
 	ContentTypeJSON            = "application/json"
+	ContentTypeForm            = "application/x-www-form-urlencoded"
 	key                        = "logger"
 	Summary                    = "Summary"
 	Detail                     = "Detail"
@@ -154,102 +194,320 @@ func getModuleNameFromGoMod() string {
 	return ""
 }
 
-var configLog LogConfig = LogConfig{
-	ProjectName: getModuleNameFromGoMod(),
-	Namespace:   "",
-	AppLog: AppLog{
-		Name:       "./logs/app",
-		LogFile:    false,
-		LogConsole: true,
-	},
-	Detail: DetailLogConfig{
-		Name:       "./logs/detail",
-		RawData:    true,
-		LogFile:    false,
-		LogConsole: false,
-	},
-	Summary: SummaryLogConfig{
-		Name:       "./logs/summary",
-		RawData:    true,
-		LogFile:    false,
-		LogConsole: false,
-	},
+var configLog = defaultLogConfig()
+
+func defaultLogConfig() LogConfig {
+	return LogConfig{
+		ProjectName: getModuleNameFromGoMod(),
+		Namespace:   "",
+		AppLog: AppLog{
+			Name:       "./logs/app",
+			LogFile:    false,
+			LogConsole: true,
+		},
+		Detail: DetailLogConfig{
+			Name:       "./logs/detail",
+			RawData:    true,
+			LogFile:    false,
+			LogConsole: false,
+		},
+		Summary: SummaryLogConfig{
+			Name:       "./logs/summary",
+			RawData:    true,
+			LogFile:    false,
+			LogConsole: false,
+		},
+	}
+}
+
+// LoadLogConfig merges cfg into the active configuration. Only the fields
+// that are set in cfg are copied, so a false boolean keeps the current value;
+// pass options such as WithDetailRawData(false) to turn an option off. A
+// missing ProjectName defaults to the name of the executable.
+func LoadLogConfig(cfg LogConfig, opts ...ConfigOption) *LogConfig {
+	conf, err := LoadLogConfigE(cfg, opts...)
+	if errors.Is(err, errProjectNameRequired) {
+		cfg.ProjectName = filepath.Base(os.Args[0])
+		fmt.Fprintf(os.Stderr, "logger: projectName is not set, using %q\n", cfg.ProjectName)
+		conf, err = LoadLogConfigE(cfg, opts...)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return conf
+}
+
+// LoadLogConfigE merges cfg into the active configuration like LoadLogConfig
+// but returns every problem found instead of exiting. The active
+// configuration is left untouched when an error is returned. The result is
+// a copy, later reloads do not change it.
+func LoadLogConfigE(cfg LogConfig, opts ...ConfigOption) (*LogConfig, error) {
+	if err := defaultManager.Load(cfg, opts...); err != nil {
+		return nil, err
+	}
+	conf := defaultManager.Config()
+	return &conf, nil
 }
 
-func LoadLogConfig(cfg LogConfig) *LogConfig {
+// buildLogConfig merges cfg and opts into base, validates the result and
+// opens the log files enabled by cfg. The files and async pipelines of prev,
+// the configuration in use, are reused when their settings are the same.
+func buildLogConfig(base, prev, cfg LogConfig, opts ...ConfigOption) (LogConfig, error) {
+	cfg = cfg.With(opts...)
+	merged := base
+	mergeLogConfig(&merged, cfg)
+
+	errs := []error{merged.Validate()}
+	if cfg.AppLog.LogFile && merged.AppLog.Name != "" {
+		errs = append(errs, checkLogDir("appLog", merged.AppLog.Name))
+	}
+	if cfg.Detail.LogFile && merged.Detail.Name != "" {
+		errs = append(errs, checkLogDir("detail", merged.Detail.Name))
+	}
+	if cfg.Summary.LogFile && merged.Summary.Name != "" {
+		errs = append(errs, checkLogDir("summary", merged.Summary.Name))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return LogConfig{}, err
+	}
+
+	sameProject := merged.ProjectName == prev.ProjectName
+	if cfg.AppLog.LogFile {
+		if sameProject && merged.AppLog.Name == prev.AppLog.Name && prev.AppLog.AppLog != nil {
+			merged.AppLog.AppLog = prev.AppLog.AppLog
+		} else {
+			merged.AppLog.AppLog = newLogFile(merged.ProjectName, merged.AppLog.Name)
+		}
+	}
+
+	if cfg.Detail.LogFile {
+		if sameProject && merged.Detail.Name == prev.Detail.Name && prev.Detail.LogDetail != nil {
+			merged.Detail.LogDetail = prev.Detail.LogDetail
+		} else {
+			merged.Detail.LogDetail = newLogFile(merged.ProjectName, merged.Detail.Name)
+		}
+	}
+
+	if cfg.Summary.LogFile {
+		if sameProject && merged.Summary.Name == prev.Summary.Name && prev.Summary.LogSummary != nil {
+			merged.Summary.LogSummary = prev.Summary.LogSummary
+		} else {
+			merged.Summary.LogSummary = newLogFile(merged.ProjectName, merged.Summary.Name)
+		}
+	}
+
+	// Each configuration owns its pipelines, a reload reuses them only when
+	// the async settings are unchanged.
+	if prev.Async != nil && merged.Async != nil && *prev.Async == *merged.Async {
+		merged.Async = prev.Async
+	} else if merged.Async != nil {
+		async := *merged.Async
+		merged.Async = &async
+	}
+
+	return merged, nil
+}
+
+func mergeLogConfig(dst *LogConfig, cfg LogConfig) {
 	if cfg.Namespace != "" {
-		configLog.Namespace = cfg.Namespace
+		dst.Namespace = cfg.Namespace
 	}
 
 	if cfg.ProjectName != "" {
-		configLog.ProjectName = cfg.ProjectName
+		dst.ProjectName = cfg.ProjectName
 	}
 
 	if cfg.AppLog.Name != "" {
-		configLog.AppLog.Name = cfg.AppLog.Name
+		dst.AppLog.Name = cfg.AppLog.Name
 	}
 
-	if cfg.AppLog.LogFile {
-		configLog.AppLog.LogFile = cfg.AppLog.LogFile
-		if err := ensureLogDirExists(configLog.AppLog.Name); err != nil {
-			log.Fatal(err)
-		}
+	if cfg.AppLog.LogFile || cfg.explicit&explicitAppLogFile != 0 {
+		dst.AppLog.LogFile = cfg.AppLog.LogFile
+	}
 
-		configLog.AppLog.AppLog = newLogFile(configLog.AppLog.Name)
+	if cfg.AppLog.LogConsole || cfg.explicit&explicitAppLogConsole != 0 {
+		dst.AppLog.LogConsole = cfg.AppLog.LogConsole
 	}
 
-	if cfg.AppLog.LogConsole {
-		configLog.AppLog.LogConsole = cfg.AppLog.LogConsole
+	if cfg.AppLog.LogLevel != 0 || cfg.explicit&explicitAppLogLevel != 0 {
+		dst.AppLog.LogLevel = cfg.AppLog.LogLevel
 	}
 
 	if cfg.Detail.Name != "" {
-		configLog.Detail.Name = cfg.Detail.Name
+		dst.Detail.Name = cfg.Detail.Name
 	}
 
-	if cfg.Detail.RawData {
-		configLog.Detail.RawData = cfg.Detail.RawData
+	if cfg.Detail.RawData || cfg.explicit&explicitDetailRawData != 0 {
+		dst.Detail.RawData = cfg.Detail.RawData
 	}
 
-	if cfg.Detail.LogFile {
-		configLog.Detail.LogFile = cfg.Detail.LogFile
+	if cfg.Detail.LogFile || cfg.explicit&explicitDetailLogFile != 0 {
+		dst.Detail.LogFile = cfg.Detail.LogFile
+	}
 
-		if err := ensureLogDirExists(configLog.Summary.Name); err != nil {
-			log.Fatal(err)
-		}
+	if cfg.Detail.LogConsole || cfg.explicit&explicitDetailLogConsole != 0 {
+		dst.Detail.LogConsole = cfg.Detail.LogConsole
+	}
+
+	if cfg.Detail.Mask != nil {
+		dst.Detail.Mask = cfg.Detail.Mask
+	}
 
-		configLog.Detail.LogDetail = newLogFile(configLog.Detail.Name)
+	if cfg.Detail.MaxBodyBytes != 0 {
+		dst.Detail.MaxBodyBytes = cfg.Detail.MaxBodyBytes
 	}
 
-	if cfg.Detail.LogConsole {
-		configLog.Detail.LogConsole = cfg.Detail.LogConsole
+	if cfg.Detail.MaxEntryBytes != 0 {
+		dst.Detail.MaxEntryBytes = cfg.Detail.MaxEntryBytes
 	}
 
 	if cfg.Summary.Name != "" {
-		configLog.Summary.Name = cfg.Summary.Name
+		dst.Summary.Name = cfg.Summary.Name
 	}
 
-	if cfg.Summary.RawData {
-		configLog.Summary.RawData = cfg.Summary.RawData
+	if cfg.Summary.RawData || cfg.explicit&explicitSummaryRawData != 0 {
+		dst.Summary.RawData = cfg.Summary.RawData
 	}
 
-	if cfg.Summary.LogConsole {
-		configLog.Summary.LogConsole = cfg.Summary.LogConsole
+	if cfg.Summary.LogConsole || cfg.explicit&explicitSummaryLogConsole != 0 {
+		dst.Summary.LogConsole = cfg.Summary.LogConsole
 	}
 
-	if cfg.Summary.LogFile {
-		configLog.Summary.LogFile = cfg.Summary.LogFile
-		if err := ensureLogDirExists(configLog.Summary.Name); err != nil {
-			log.Fatal(err)
+	if cfg.Summary.Mask != nil {
+		dst.Summary.Mask = cfg.Summary.Mask
+	}
+
+	if cfg.Summary.LogFile || cfg.explicit&explicitSummaryLogFile != 0 {
+		dst.Summary.LogFile = cfg.Summary.LogFile
+	}
+
+	if cfg.Shutdown.ResultCode != "" {
+		dst.Shutdown.ResultCode = cfg.Shutdown.ResultCode
+	}
+
+	if cfg.Shutdown.ResultDesc != "" {
+		dst.Shutdown.ResultDesc = cfg.Shutdown.ResultDesc
+	}
+
+	if cfg.Async != nil {
+		dst.Async = cfg.Async
+	}
+
+	if cfg.Debug.Header != "" {
+		dst.Debug.Header = cfg.Debug.Header
+	}
+
+	if cfg.Debug.Secret != "" && cfg.Debug.Secret != hiddenSecret {
+		dst.Debug.Secret = cfg.Debug.Secret
+	}
+
+	if len(cfg.Sinks.App) > 0 {
+		dst.Sinks.App = cfg.Sinks.App
+	}
+
+	if len(cfg.Sinks.Detail) > 0 {
+		dst.Sinks.Detail = cfg.Sinks.Detail
+	}
+
+	if len(cfg.Sinks.Summary) > 0 {
+		dst.Sinks.Summary = cfg.Sinks.Summary
+	}
+
+	if cfg.Propagator != nil {
+		dst.Propagator = cfg.Propagator
+	}
+
+	dst.explicit |= cfg.explicit
+}
+
+// Validate reports every problem of the configuration: missing names,
+// conflicting log file locations and invalid level, mask and async settings.
+func (c LogConfig) Validate() error {
+	var errs []error
+
+	streams := []struct {
+		field   string
+		name    string
+		logFile bool
+	}{
+		{"appLog", c.AppLog.Name, c.AppLog.LogFile},
+		{"detail", c.Detail.Name, c.Detail.LogFile},
+		{"summary", c.Summary.Name, c.Summary.LogFile},
+	}
+
+	fileEnabled := false
+	dirs := map[string]string{}
+	for _, s := range streams {
+		if !s.logFile {
+			continue
+		}
+		fileEnabled = true
+		if strings.TrimSpace(s.name) == "" {
+			errs = append(errs, fmt.Errorf("%s.name: required when logFile is enabled", s.field))
+			continue
+		}
+		dir := filepath.Clean(s.name)
+		if other, ok := dirs[dir]; ok {
+			errs = append(errs, fmt.Errorf("%s.name: %q is also used by %s, their log files would collide", s.field, s.name, other))
+			continue
+		}
+		dirs[dir] = s.field
+	}
+
+	if fileEnabled && strings.TrimSpace(c.ProjectName) == "" {
+		errs = append(errs, errProjectNameRequired)
+	}
+
+	if c.AppLog.LogLevel < zapcore.DebugLevel || c.AppLog.LogLevel > zapcore.FatalLevel {
+		errs = append(errs, fmt.Errorf("appLog.logLevel: invalid level %d", c.AppLog.LogLevel))
+	}
+
+	if c.Detail.Mask != nil {
+		if err := c.Detail.Mask.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("detail.mask: %w", err))
 		}
+	}
+
+	if c.Detail.MaxBodyBytes < 0 {
+		errs = append(errs, fmt.Errorf("detail.maxBodyBytes: must not be negative, got %d", c.Detail.MaxBodyBytes))
+	}
 
-		configLog.Summary.LogSummary = newLogFile(configLog.Summary.Name)
+	if c.Detail.MaxEntryBytes < 0 {
+		errs = append(errs, fmt.Errorf("detail.maxEntryBytes: must not be negative, got %d", c.Detail.MaxEntryBytes))
+	}
+
+	if c.Summary.Mask != nil {
+		if err := c.Summary.Mask.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("summary.mask: %w", err))
+		}
 	}
 
-	return &configLog
+	if c.Async != nil {
+		if err := c.Async.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("async: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
-func newLogFile(path string) *zap.Logger {
-	log, err := createLogger(path)
+// checkLogDir makes sure the log directory exists and files can be created in it.
+func checkLogDir(field, path string) error {
+	if err := ensureLogDirExists(path); err != nil {
+		return fmt.Errorf("%s.name: %w", field, err)
+	}
+
+	f, err := os.CreateTemp(path, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("%s.name: log directory[%s] is not writable: %w", field, path, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+func newLogFile(appName, path string) *zap.Logger {
+	log, err := createLogger(appName, path)
 	if err != nil {
 		fmt.Println("Failed to create log file logger:", err)
 	}
@@ -267,9 +525,9 @@ func ensureLogDirExists(path string) error {
 	return nil
 }
 
-func createLogger(path string) (*zap.Logger, error) {
+func createLogger(appName, path string) (*zap.Logger, error) {
 	// Create log file with rotating mechanism
-	logFile := filepath.Join(path, getLogFileName(time.Now()))
+	logFile := filepath.Join(path, getLogFileName(appName, time.Now()))
 
 	// Create a zapcore encoder config
 	encCfg := zapcore.EncoderConfig{
@@ -284,26 +542,27 @@ func createLogger(path string) (*zap.Logger, error) {
 	fileEncoder := zapcore.NewConsoleEncoder(encCfg)
 
 	// Setting up lumberjack logger for log rotation
-	writerSync := zapcore.AddSync(&lumberjack.Logger{
+	writer := &lumberjack.Logger{
 		Filename:   logFile,
 		MaxSize:    500, // megabytes
 		MaxBackups: 3,   // number of backups
 		MaxAge:     1,   // days
 		LocalTime:  true,
 		Compress:   true, // compress the backups
-	})
+	}
+	writerSync := zapcore.AddSync(writer)
 
-	// Create the core with InfoLevel logging
-	core := zapcore.NewCore(fileEncoder, writerSync, zap.InfoLevel)
+	// the app logger filters the level itself, see appCore
+	core := zapcore.NewCore(fileEncoder, writerSync, zap.DebugLevel)
 
 	// Create logger
 	log := zap.New(core)
+	trackLogFile(log, writer)
 
 	return log, nil
 }
 
-func getLogFileName(t time.Time) string {
-	appName := configLog.ProjectName
+func getLogFileName(appName string, t time.Time) string {
 	year, month, day := t.Date()
 	hour, minute, second := t.Clock()
 
@@ -1,8 +1,10 @@
 package logger
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -202,3 +204,111 @@ func TestEnsureLogDirExists(t *testing.T) {
 	// Clean up -> directory should still exist
 	os.RemoveAll(dirPath)
 }
+
+func TestLoadLogConfigE(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		AppLog: AppLog{
+			Name: "./logs/app",
+		},
+	}
+
+	tmpDir := t.TempDir()
+	notADir := filepath.Join(tmpDir, "file")
+	if err := os.WriteFile(notADir, []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	cfg := LogConfig{
+		AppLog: AppLog{
+			LogLevel: 42,
+		},
+		Detail: DetailLogConfig{
+			Name:    filepath.Join(tmpDir, "same"),
+			LogFile: true,
+		},
+		Summary: SummaryLogConfig{
+			Name:    filepath.Join(tmpDir, "same"),
+			LogFile: true,
+			Mask: &MaskConfig{
+				Patterns: []PatternRule{{Name: "broken", Pattern: "("}},
+			},
+		},
+		Async: &AsyncConfig{
+			Policy: "unknown",
+		},
+	}
+
+	loaded, err := LoadLogConfigE(cfg)
+	if err == nil {
+		t.Fatal("Expected error, but got nil")
+	}
+	if loaded != nil {
+		t.Errorf("Expected no config to be returned, but got %+v", loaded)
+	}
+
+	for _, expected := range []string{"appLog.logLevel", "summary.name", "summary.mask", "async: policy"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to mention %s, but got %v", expected, err)
+		}
+	}
+
+	// the active configuration is left untouched
+	if configLog.Detail.LogFile || configLog.Async != nil {
+		t.Errorf("Expected configLog to be unchanged, but got %+v", configLog)
+	}
+
+	// unwritable directory and missing name
+	_, err = LoadLogConfigE(LogConfig{
+		AppLog: AppLog{
+			Name:    filepath.Join(notADir, "app"),
+			LogFile: true,
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "appLog.name") {
+		t.Errorf("Expected appLog.name error, but got %v", err)
+	}
+
+	configLog.Detail.Name = ""
+	_, err = LoadLogConfigE(LogConfig{
+		Detail: DetailLogConfig{
+			LogFile: true,
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "detail.name: required") {
+		t.Errorf("Expected detail.name error, but got %v", err)
+	}
+
+	// valid configuration
+	loaded, err = LoadLogConfigE(LogConfig{
+		Detail: DetailLogConfig{
+			Name:    filepath.Join(tmpDir, "detail"),
+			LogFile: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if loaded.Detail.LogDetail == nil {
+		t.Error("Expected detail log file to be opened")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "detail")); err != nil {
+		t.Errorf("Expected detail log directory to be created, but got %v", err)
+	}
+}
+
+func TestLoadLogConfigDefaultsProjectName(t *testing.T) {
+	configLog = LogConfig{Detail: DetailLogConfig{Name: t.TempDir()}}
+	cfg := LogConfig{Detail: DetailLogConfig{LogFile: true}}
+
+	_, err := LoadLogConfigE(cfg)
+	if !errors.Is(err, errProjectNameRequired) {
+		t.Fatalf("Expected projectName error, but got %v", err)
+	}
+
+	// LoadLogConfig stays lenient
+	loaded := LoadLogConfig(cfg)
+	if loaded.ProjectName != filepath.Base(os.Args[0]) {
+		t.Errorf("Expected ProjectName to be %s, but got %s", filepath.Base(os.Args[0]), loaded.ProjectName)
+	}
+}
@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Manager creates detail, summary and app loggers from its own LogConfig, so
+// components of one binary (or parallel tests) can log with different
+// project names, files and settings. The package level functions use the
+// default Manager, which is configured by LoadLogConfig.
+type Manager struct {
+	mu      sync.RWMutex
+	conf    *LogConfig
+	code    LogConfig // the code layer of the last Load, reapplied on reloads
+	level   zap.AtomicLevel
+	streams *streamSwitches
+	metrics *summaryMetrics
+	open    openLogSet
+}
+
+var defaultManager = newManager(&configLog)
+
+// managers are the Managers not shut down yet, whose logs are ended and sinks
+// flushed and closed by Flush and Shutdown.
+var managers = struct {
+	sync.Mutex
+	list []*Manager
+}{}
+
+func newManager(conf *LogConfig) *Manager {
+	m := &Manager{
+		conf:    conf,
+		level:   zap.NewAtomicLevelAt(appLogLevel(*conf)),
+		streams: &streamSwitches{},
+		metrics: newSummaryMetrics(),
+	}
+	managers.Lock()
+	managers.list = append(managers.list, m)
+	managers.Unlock()
+	return m
+}
+
+// Default returns the Manager used by the package level functions.
+func Default() *Manager {
+	return defaultManager
+}
+
+// New creates a Manager from the default configuration merged with cfg and
+// opts. The package level configuration is not changed. Call Shutdown on a
+// Manager that is discarded before the process exits to release its logs.
+func New(cfg LogConfig, opts ...ConfigOption) (*Manager, error) {
+	conf, err := buildLogConfig(defaultLogConfig(), LogConfig{}, cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m := newManager(&conf)
+	m.code = cfg.With(opts...)
+	return m, nil
+}
+
+// Config returns a copy of the configuration of the Manager.
+func (m *Manager) Config() LogConfig {
+	return m.config()
+}
+
+// Load merges cfg and opts into the configuration of the Manager and swaps
+// it in. Detail and summary logs created before keep the configuration they
+// were created with; app loggers follow the new level. The configuration is
+// left untouched when an error is returned.
+func (m *Manager) Load(cfg LogConfig, opts ...ConfigOption) error {
+	return m.load(LogConfig{}, cfg.With(opts...))
+}
+
+// load merges layer and then code into the configuration. code is kept, so
+// later reloads apply it over the file and environment values.
+func (m *Manager) load(layer, code LogConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mergeLogConfig(&layer, code)
+	if err := m.replaceLocked(*m.conf, layer); err != nil {
+		return err
+	}
+	m.code = code
+	return nil
+}
+
+// replace swaps in base merged with cfg and then the code configuration, see
+// Load.
+func (m *Manager) replace(base, cfg LogConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mergeLogConfig(&cfg, m.code)
+	return m.replaceLocked(base, cfg)
+}
+
+func (m *Manager) replaceLocked(base, cfg LogConfig, opts ...ConfigOption) error {
+	merged, err := buildLogConfig(base, *m.conf, cfg, opts...)
+	if err != nil {
+		return err
+	}
+	prev := *m.conf
+	*m.conf = merged
+	m.level.SetLevel(appLogLevel(merged))
+
+	// The pipelines of replaced async settings are drained in the background,
+	// logs still holding them write synchronously once they are closed.
+	if prev.Async != nil && prev.Async != merged.Async {
+		go func() {
+			if _, err := closeAsync(context.Background(), prev.Async); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to close replaced async pipeline: %v\n", err)
+			}
+		}()
+	}
+	return nil
+}
+
+func (m *Manager) config() LogConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return *m.conf
+}
+
+// SetStreamEnabled turns the output of a stream (StreamApp, StreamDetail or
+// StreamSummary) on or off. It applies to the logs already in flight.
+func (m *Manager) SetStreamEnabled(stream string, on bool) error {
+	off := m.streams.get(stream)
+	if off == nil {
+		return fmt.Errorf("unknown stream %q", stream)
+	}
+	off.Store(!on)
+	return nil
+}
+
+// StreamEnabled reports whether the output of a stream is turned on.
+func (m *Manager) StreamEnabled(stream string) bool {
+	return !m.streams.disabled(stream)
+}
+
+// streamSwitches holds the streams turned off at runtime.
+type streamSwitches struct {
+	app     atomic.Bool
+	detail  atomic.Bool
+	summary atomic.Bool
+}
+
+func (s *streamSwitches) get(stream string) *atomic.Bool {
+	switch stream {
+	case StreamApp:
+		return &s.app
+	case StreamDetail:
+		return &s.detail
+	case StreamSummary:
+		return &s.summary
+	}
+	return nil
+}
+
+func (s *streamSwitches) disabled(stream string) bool {
+	if s == nil {
+		return false
+	}
+	off := s.get(stream)
+	return off != nil && off.Load()
+}
+
+// appLogLevel returns the level of the app logger. Unless set it is info
+// when the app log goes to a file and debug on the console; debug lines reach
+// the file only for transactions escalated by DebugLogger.
+func appLogLevel(conf LogConfig) zapcore.Level {
+	if conf.AppLog.LogLevel == 0 && conf.explicit&explicitAppLogLevel == 0 {
+		if conf.AppLog.LogFile {
+			return zapcore.InfoLevel
+		}
+		return zapcore.DebugLevel
+	}
+	return conf.AppLog.LogLevel
+}
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewManager(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+	}
+
+	orders := NewMemorySink()
+	payments := NewMemorySink()
+
+	orderManager, err := New(LogConfig{
+		ProjectName: "order_service",
+		Sinks:       Sinks{Summary: []Sink{orders}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	paymentManager, err := New(LogConfig{
+		ProjectName: "payment_service",
+		Sinks:       Sinks{Summary: []Sink{payments}},
+	}, WithSummaryRawData(false))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	orderManager.NewSummaryLog("session", "", "create_order").End("20000", "success")
+	paymentManager.NewSummaryLog("session", "", "pay").End("20000", "success")
+
+	assert.Len(t, orders.Entries(), 1)
+	assert.Contains(t, string(orders.Entries()[0].Payload), `"AppName":"order_service"`)
+	assert.Len(t, payments.Entries(), 1)
+	assert.Contains(t, string(payments.Entries()[0].Payload), `"AppName":"payment_service"`)
+
+	assert.True(t, orderManager.Config().Summary.RawData)
+	assert.False(t, paymentManager.Config().Summary.RawData)
+
+	// the package configuration is left untouched
+	assert.Equal(t, "test_project", configLog.ProjectName)
+	assert.Equal(t, "test_project", Default().Config().ProjectName)
+}
+
+func TestNewManagerInvalid(t *testing.T) {
+	m, err := New(LogConfig{AppLog: AppLog{LogLevel: 42}})
+	if err == nil {
+		t.Fatal("Expected error, but got nil")
+	}
+	assert.Nil(t, m)
+}
+
+func TestManagerDetailLog(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+	}
+
+	m, err := New(LogConfig{ProjectName: "order_service"}, WithDetailRawData(false))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	dl := m.NewDetailLog("session", "", "create_order").(*detailLog)
+	assert.Equal(t, "order_service", dl.AppName)
+	assert.False(t, dl.IsRawDataEnabled())
+	dl.End()
+}
+
+func TestManagerInitSession(t *testing.T) {
+	m, err := New(LogConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	ctx, l := m.InitSession(context.WithValue(context.Background(), xSession, "test_session"), nil)
+	assert.NotNil(t, l)
+	assert.Equal(t, l, NewLog(ctx))
+}
+
+func TestMiddlewareWithManager(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+	}
+
+	sink := NewMemorySink()
+	m, err := New(LogConfig{
+		ProjectName: "order_service",
+		Sinks:       Sinks{Detail: []Sink{sink}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	handler := Middleware("create_order", WithManager(m))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+
+	assert.Len(t, sink.Entries(), 1)
+	assert.Contains(t, string(sink.Entries()[0].Payload), `"AppName":"order_service"`)
+}
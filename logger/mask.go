@@ -0,0 +1,406 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+type MaskStrategy string
+
+const (
+	// MaskFull replaces every character of the value with '*'.
+	MaskFull MaskStrategy = "full"
+	// MaskPartial keeps the last 4 characters and replaces the rest with '*'.
+	MaskPartial MaskStrategy = "partial"
+	// MaskHash replaces the value with its sha256 hex digest.
+	MaskHash MaskStrategy = "hash"
+)
+
+// MaskConfig describes which parts of a detail or summary entry are redacted
+// before it is written.
+type MaskConfig struct {
+	// Headers lists header names (case-insensitive) whose values are masked.
+	Headers []string `json:"headers,omitempty"`
+	// Fields lists JSON paths ("$.body.card.number") or key names ("password").
+	// A key name matches at any depth.
+	Fields []FieldRule `json:"fields,omitempty"`
+	// Patterns are applied to every string value, and to RawData strings.
+	Patterns []PatternRule `json:"patterns,omitempty"`
+	// Strategy is used by Headers and by rules without their own strategy.
+	Strategy MaskStrategy `json:"strategy,omitempty"`
+
+	once  sync.Once
+	rules *maskRules
+	err   error
+}
+
+type FieldRule struct {
+	Path     string       `json:"path"`
+	Strategy MaskStrategy `json:"strategy,omitempty"`
+}
+
+type PatternRule struct {
+	Name     string       `json:"name"`
+	Pattern  string       `json:"pattern"`
+	Strategy MaskStrategy `json:"strategy,omitempty"`
+	// Validate, when set, is called with each match and the match is only
+	// masked when it returns true.
+	Validate func(match string) bool `json:"-"`
+}
+
+var (
+	DefaultMaskHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+	MaskPAN = PatternRule{
+		Name:     "pan",
+		Pattern:  `\b(?:\d[ -]?){12,18}\d\b`,
+		Strategy: MaskPartial,
+		Validate: isLuhn,
+	}
+	MaskEmail = PatternRule{
+		Name:     "email",
+		Pattern:  `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+		Strategy: MaskPartial,
+	}
+	MaskPhone = PatternRule{
+		Name:     "phone",
+		Pattern:  `(?:\+66|\b0)[689]\d[- ]?\d{3}[- ]?\d{4}\b`,
+		Strategy: MaskPartial,
+	}
+	MaskThaiCitizenID = PatternRule{
+		Name:     "thai_citizen_id",
+		Pattern:  `\b\d[- ]?\d{4}[- ]?\d{5}[- ]?\d{2}[- ]?\d\b`,
+		Strategy: MaskPartial,
+		Validate: isThaiCitizenID,
+	}
+)
+
+type maskRules struct {
+	strategy MaskStrategy
+	headers  map[string]MaskStrategy
+	keys     map[string]MaskStrategy
+	paths    []pathRule
+	patterns []patternRule
+}
+
+type pathRule struct {
+	segments []string
+	strategy MaskStrategy
+}
+
+type patternRule struct {
+	re       *regexp.Regexp
+	strategy MaskStrategy
+	validate func(string) bool
+}
+
+func (m *MaskConfig) compile() error {
+	m.once.Do(func() {
+		strategy := m.Strategy
+		if strategy == "" {
+			strategy = MaskFull
+		}
+		rules := &maskRules{
+			strategy: strategy,
+			headers:  map[string]MaskStrategy{},
+			keys:     map[string]MaskStrategy{},
+		}
+
+		var errs []error
+		if err := validStrategy(m.Strategy); err != nil {
+			errs = append(errs, err)
+		}
+
+		for _, h := range m.Headers {
+			rules.headers[strings.ToLower(h)] = strategy
+		}
+
+		for _, f := range m.Fields {
+			if err := validStrategy(f.Strategy); err != nil {
+				errs = append(errs, fmt.Errorf("mask field %q: %w", f.Path, err))
+			}
+			s := f.Strategy
+			if s == "" {
+				s = strategy
+			}
+
+			path := strings.TrimSpace(f.Path)
+			if path == "" {
+				errs = append(errs, errors.New("mask field: empty path"))
+				continue
+			}
+			if path == "$" || strings.HasPrefix(path, "$.") {
+				rules.paths = append(rules.paths, pathRule{segments: splitMaskPath(path), strategy: s})
+				continue
+			}
+			rules.keys[strings.ToLower(path)] = s
+		}
+
+		for _, p := range m.Patterns {
+			if err := validStrategy(p.Strategy); err != nil {
+				errs = append(errs, fmt.Errorf("mask pattern %q: %w", p.Name, err))
+			}
+			re, err := regexp.Compile(p.Pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("mask pattern %q: %w", p.Name, err))
+				continue
+			}
+			s := p.Strategy
+			if s == "" {
+				s = strategy
+			}
+			rules.patterns = append(rules.patterns, patternRule{re: re, strategy: s, validate: p.Validate})
+		}
+
+		m.rules = rules
+		m.err = errors.Join(errs...)
+	})
+	return m.err
+}
+
+func validStrategy(s MaskStrategy) error {
+	switch s {
+	case "", MaskFull, MaskPartial, MaskHash:
+		return nil
+	}
+	return fmt.Errorf("unknown mask strategy %q", s)
+}
+
+func splitMaskPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil
+	}
+
+	var segments []string
+	for _, seg := range strings.Split(path, ".") {
+		// arrays are walked transparently, so index selectors are dropped
+		if i := strings.Index(seg, "["); i >= 0 {
+			seg = seg[:i]
+		}
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return segments
+}
+
+// Apply returns a masked copy of data. The original value is never modified.
+func (m *MaskConfig) Apply(data interface{}) interface{} {
+	if m == nil || data == nil {
+		return data
+	}
+	m.compile()
+	return m.rules.walk(ToStruct(data), nil, false)
+}
+
+// applyRaw masks a RawData value. JSON strings are decoded, masked and encoded
+// again; any other string only goes through the pattern rules.
+func (m *MaskConfig) applyRaw(raw interface{}) interface{} {
+	if m == nil || raw == nil {
+		return raw
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return m.Apply(raw)
+	}
+
+	m.compile()
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var data interface{}
+		if err := json.Unmarshal([]byte(trimmed), &data); err == nil {
+			return ToJson(m.rules.walk(data, nil, false))
+		}
+	}
+	return m.rules.maskPatterns(s)
+}
+
+func (m *MaskConfig) applyString(s string) string {
+	if m == nil {
+		return s
+	}
+	m.compile()
+	return m.rules.maskPatterns(s)
+}
+
+func (m *MaskConfig) applyEntries(entries []InputOutputLog) {
+	if m == nil {
+		return
+	}
+	for i := range entries {
+		entries[i].Data = m.Apply(entries[i].Data)
+		entries[i].RawData = m.applyRaw(entries[i].RawData)
+	}
+}
+
+func (r *maskRules) walk(v interface{}, path []string, inHeader bool) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if isTruncatedBody(value) && (len(r.keys) > 0 || len(r.paths) > 0) {
+			// the preview is raw text cut at the limit, which the field
+			// rules cannot see into
+			value["preview"] = "[omitted, truncated body with masked fields]"
+			return value
+		}
+		for k, child := range value {
+			childPath := append(path[:len(path):len(path)], k)
+			if strategy, ok := r.match(childPath, k, inHeader); ok {
+				value[k] = maskAny(child, strategy)
+				continue
+			}
+			value[k] = r.walk(child, childPath, strings.EqualFold(k, "header") || strings.EqualFold(k, "headers") || strings.EqualFold(k, "metadata"))
+		}
+		return value
+	case []interface{}:
+		for i := range value {
+			value[i] = r.walk(value[i], path, inHeader)
+		}
+		return value
+	case string:
+		return r.maskPatterns(value)
+	}
+	return v
+}
+
+// isTruncatedBody reports whether v is a TruncatedBody decoded by ToStruct.
+func isTruncatedBody(v map[string]interface{}) bool {
+	truncated, _ := v["truncated"].(bool)
+	_, preview := v["preview"].(string)
+	return truncated && preview && len(v) <= 3
+}
+
+func (r *maskRules) match(path []string, key string, inHeader bool) (MaskStrategy, bool) {
+	if inHeader {
+		if strategy, ok := r.headers[strings.ToLower(key)]; ok {
+			return strategy, true
+		}
+	}
+	if strategy, ok := r.keys[strings.ToLower(key)]; ok {
+		return strategy, true
+	}
+	for _, rule := range r.paths {
+		if matchPath(rule.segments, path) {
+			return rule.strategy, true
+		}
+	}
+	return "", false
+}
+
+func matchPath(segments, path []string) bool {
+	if len(segments) != len(path) {
+		return false
+	}
+	for i, seg := range segments {
+		if seg != "*" && !strings.EqualFold(seg, path[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *maskRules) maskPatterns(s string) string {
+	for _, p := range r.patterns {
+		s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+			if p.validate != nil && !p.validate(match) {
+				return match
+			}
+			return maskString(match, p.strategy)
+		})
+	}
+	return s
+}
+
+func maskAny(v interface{}, strategy MaskStrategy) interface{} {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return maskString(value, strategy)
+	case map[string]interface{}:
+		for k, child := range value {
+			value[k] = maskAny(child, strategy)
+		}
+		return value
+	case []interface{}:
+		for i := range value {
+			value[i] = maskAny(value[i], strategy)
+		}
+		return value
+	}
+	return maskString(fmt.Sprint(v), strategy)
+}
+
+func maskString(s string, strategy MaskStrategy) string {
+	switch strategy {
+	case MaskHash:
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	case MaskPartial:
+		runes := []rune(s)
+		keep := 4
+		if len(runes) <= keep {
+			return strings.Repeat("*", len(runes))
+		}
+		for i := 0; i < len(runes)-keep; i++ {
+			if unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) {
+				runes[i] = '*'
+			}
+		}
+		return string(runes)
+	default:
+		return strings.Repeat("*", len([]rune(s)))
+	}
+}
+
+func digitsOf(s string) []int {
+	var digits []int
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, int(c-'0'))
+		}
+	}
+	return digits
+}
+
+func isLuhn(s string) bool {
+	digits := digitsOf(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func isThaiCitizenID(s string) bool {
+	digits := digitsOf(s)
+	if len(digits) != 13 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 12; i++ {
+		sum += digits[i] * (13 - i)
+	}
+	return (11-sum%11)%10 == digits[12]
+}
@@ -0,0 +1,227 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskString(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		strategy MaskStrategy
+		expected string
+	}{
+		{
+			name:     "Full mask",
+			value:    "secret",
+			strategy: MaskFull,
+			expected: "******",
+		},
+		{
+			name:     "Partial mask keeps last 4",
+			value:    "4111111111111111",
+			strategy: MaskPartial,
+			expected: "************1111",
+		},
+		{
+			name:     "Partial mask keeps separators",
+			value:    "4111-1111-1111-1111",
+			strategy: MaskPartial,
+			expected: "****-****-****-1111",
+		},
+		{
+			name:     "Partial mask short value",
+			value:    "abc",
+			strategy: MaskPartial,
+			expected: "***",
+		},
+		{
+			name:     "Hash mask",
+			value:    "secret",
+			strategy: MaskHash,
+			expected: "sha256:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, maskString(tc.value, tc.strategy))
+		})
+	}
+}
+
+func TestMaskConfigApply(t *testing.T) {
+	mask := &MaskConfig{
+		Headers: DefaultMaskHeaders,
+		Fields: []FieldRule{
+			{Path: "$.body.card.number", Strategy: MaskPartial},
+			{Path: "password"},
+			{Path: "$.body.items[*].token", Strategy: MaskHash},
+		},
+		Patterns: []PatternRule{MaskEmail},
+	}
+
+	data := map[string]interface{}{
+		"header": map[string]interface{}{
+			"Authorization": []interface{}{"Bearer abc"},
+			"Accept":        []interface{}{ContentTypeJSON},
+		},
+		"body": map[string]interface{}{
+			"card": map[string]interface{}{
+				"number": "4111111111111111",
+				"holder": "john",
+			},
+			"user": map[string]interface{}{
+				"Password": "p@ss",
+				"email":    "john.doe@example.com",
+			},
+			"items": []interface{}{
+				map[string]interface{}{"token": "abc"},
+			},
+		},
+	}
+
+	masked := mask.Apply(data).(map[string]interface{})
+
+	header := masked["header"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"**********"}, header["Authorization"])
+	assert.Equal(t, []interface{}{ContentTypeJSON}, header["Accept"])
+
+	body := masked["body"].(map[string]interface{})
+	card := body["card"].(map[string]interface{})
+	assert.Equal(t, "************1111", card["number"])
+	assert.Equal(t, "john", card["holder"])
+
+	user := body["user"].(map[string]interface{})
+	assert.Equal(t, "****", user["Password"])
+	assert.Equal(t, "****.***@*******.com", user["email"])
+
+	item := body["items"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, maskString("abc", MaskHash), item["token"])
+
+	// the original value is left untouched
+	assert.Equal(t, "4111111111111111", data["body"].(map[string]interface{})["card"].(map[string]interface{})["number"])
+}
+
+func TestMaskPatterns(t *testing.T) {
+	mask := &MaskConfig{
+		Patterns: []PatternRule{MaskPAN, MaskThaiCitizenID, MaskPhone, MaskEmail},
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{
+			name:     "Valid PAN",
+			value:    "card 4111 1111 1111 1111 paid",
+			expected: "card **** **** **** 1111 paid",
+		},
+		{
+			name:     "Number failing luhn check",
+			value:    "order 1234567890123456",
+			expected: "order 1234567890123456",
+		},
+		{
+			name:     "Thai citizen ID",
+			value:    "id 1-1017-00207-54-4",
+			expected: "id *-****-*****-54-4",
+		},
+		{
+			name:     "Phone number",
+			value:    "call 0812345678",
+			expected: "call ******5678",
+		},
+		{
+			name:     "Email",
+			value:    "mail a@b.co",
+			expected: "mail *@b.co",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, mask.applyString(tc.value))
+		})
+	}
+}
+
+func TestMaskConfigInvalid(t *testing.T) {
+	mask := &MaskConfig{
+		Fields:   []FieldRule{{Path: "password", Strategy: "unknown"}},
+		Patterns: []PatternRule{{Name: "broken", Pattern: "("}},
+	}
+
+	err := mask.compile()
+	if err == nil {
+		t.Fatal("Expected error, but got nil")
+	}
+	assert.Contains(t, err.Error(), "unknown mask strategy")
+	assert.Contains(t, err.Error(), "broken")
+}
+
+func TestDetailLogMask(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		Detail: DetailLogConfig{
+			RawData:    true,
+			LogConsole: true,
+			Mask: &MaskConfig{
+				Headers: DefaultMaskHeaders,
+				Fields:  []FieldRule{{Path: "password"}},
+			},
+		},
+	}
+
+	req := &http.Request{
+		Method: "POST",
+		Proto:  HTTPProto,
+		Header: http.Header{
+			"Authorization": []string{"Bearer token"},
+		},
+		URL:  &url.URL{},
+		Body: io.NopCloser(bytes.NewBufferString(`{"user":"john","password":"secret"}`)),
+	}
+
+	dl := NewDetailLog("test_session", "test_invoke", "test_scenario")
+	dl.AddInputHttpRequest("client", "login", "test_invoke", req, true)
+
+	out := captureStdout(t, dl.End)
+
+	assert.NotContains(t, out, "Bearer token")
+	assert.NotContains(t, out, "secret")
+	assert.Contains(t, out, "john")
+	assert.Equal(t, []string{"Bearer token"}, req.Header["Authorization"], "request headers must not be modified")
+}
+
+func TestSummaryLogMask(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		Summary: SummaryLogConfig{
+			LogConsole: true,
+			Mask: &MaskConfig{
+				Fields:   []FieldRule{{Path: "citizenId", Strategy: MaskPartial}},
+				Patterns: []PatternRule{MaskEmail},
+			},
+		},
+	}
+
+	sl := NewSummaryLog("test_session", "test_initInvoke", "test_cmd")
+	sl.AddField("citizenId", "1101700207544")
+	sl.AddError("node1", "cmd1", "400", "invalid email john@example.com")
+
+	out := captureStdout(t, func() {
+		sl.End("400", "Bad Request")
+	})
+
+	assert.NotContains(t, out, "1101700207544")
+	assert.Contains(t, out, "*********7544")
+	assert.NotContains(t, out, "john@example.com")
+}
@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// KafkaProto and AMQPProto are the protocols of the message helpers.
+	KafkaProto = "kafka"
+	AMQPProto  = "amqp"
+)
+
+// MessageData is the data of a message entry in the detail log.
+type MessageData struct {
+	Topic   string            `json:"topic"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Payload any               `json:"payload,omitempty"`
+}
+
+// ConsumeScope creates a DetailLog and a SummaryLog for a consumed message,
+// as Middleware does for an HTTP request, and records the message as input.
+// The session and invoke are continued from the message headers when
+// present, and the trace from the traceparent header. The scenario and cmd
+// default to the topic and the node to the protocol. The returned function
+// ends both logs with the result of the handler:
+//
+//	ctx, end := logger.ConsumeScope(ctx, msg.Topic, headers, msg.Value)
+//	end(handle(ctx, msg))
+//
+// Only end closes the logs, so they stay open when the handler panics; use
+// Consume for handlers that may panic.
+func ConsumeScope(ctx context.Context, topic string, headers map[string]string, payload []byte, opts ...MiddlewareOption) (context.Context, func(error)) {
+	cfg := newMessageConfig(topic, opts)
+
+	session := messageHeader(headers, cfg.sessionHeader)
+	if session == "" {
+		session, _ = ctx.Value(xSession).(string)
+	}
+	if session == "" {
+		session = newSessionID()
+	}
+	ctx = context.WithValue(ctx, xSession, session)
+	ctx = WithTraceContext(ctx, extractTraceContext(func(key string) string {
+		return messageHeader(headers, key)
+	}))
+	if cfg.logger != nil {
+		ctx, _ = cfg.manager.InitSession(ctx, cfg.logger)
+	}
+
+	invoke := messageHeader(headers, XTidMetadata)
+	if invoke == "" {
+		invoke = GenerateXTid(cfg.node)
+	}
+	detailLog := cfg.manager.NewDetailLog(session, invoke, topic)
+	summaryLog := cfg.manager.NewSummaryLog(session, invoke, topic)
+	if IsDebug(ctx) {
+		EnableDebug(detailLog)
+	}
+	traceLogs(ctx, detailLog, summaryLog)
+	ctx = WithDetailLog(ctx, detailLog)
+	ctx = WithSummaryLog(ctx, summaryLog)
+
+	addInputEvent(detailLog, cfg.messageEvent(invoke, "req", "consume", topic, headers, payload, detailLog))
+
+	return ctx, func(err error) {
+		code, desc := strconv.Itoa(http.StatusOK), http.StatusText(http.StatusOK)
+		if err != nil {
+			code, desc = strconv.Itoa(http.StatusInternalServerError), http.StatusText(http.StatusInternalServerError)
+			summaryLog.AddError(cfg.node, cfg.cmd, code, err.Error())
+		} else {
+			summaryLog.AddSuccess(cfg.node, cfg.cmd, code, desc)
+		}
+		detailLog.AutoEnd()
+		if !summaryLog.IsEnd() {
+			summaryLog.End(code, desc)
+		}
+	}
+}
+
+// Consume runs handle within a ConsumeScope and ends the logs with its
+// result. A panic in handle is recorded as a 500 with the panic value, then
+// re-raised.
+//
+//	err := logger.Consume(ctx, msg.Topic, headers, msg.Value, func(ctx context.Context) error {
+//		return handle(ctx, msg)
+//	})
+func Consume(ctx context.Context, topic string, headers map[string]string, payload []byte, handle func(context.Context) error, opts ...MiddlewareOption) (err error) {
+	ctx, end := ConsumeScope(ctx, topic, headers, payload, opts...)
+	defer func() {
+		if rec := recover(); rec != nil {
+			end(fmt.Errorf("%v", rec))
+			panic(rec)
+		}
+	}()
+
+	err = handle(ctx)
+	end(err)
+	return err
+}
+
+// Publish records a message about to be published as output on the
+// DetailLog of ctx and returns a copy of headers carrying the session,
+// invoke and traceparent of ctx for the consumer. The node defaults to the
+// protocol and the cmd to the topic.
+func Publish(ctx context.Context, topic string, headers map[string]string, payload []byte, opts ...MiddlewareOption) map[string]string {
+	cfg := newMessageConfig(topic, opts)
+	invoke := GenerateXTid(cfg.node)
+
+	out := make(map[string]string, len(headers)+4)
+	for k, v := range headers {
+		out[k] = v
+	}
+	out[XTidMetadata] = invoke
+	if session, ok := ctx.Value(xSession).(string); ok && session != "" {
+		out[cfg.sessionHeader] = session
+	}
+	injectTraceContext(ctx, func(key, value string) {
+		out[key] = value
+	})
+
+	detailLog := DetailLogFromContext(ctx)
+	addOutputEvent(detailLog, cfg.messageEvent(invoke, "rep", "publish", topic, out, payload, detailLog))
+	return out
+}
+
+func newMessageConfig(topic string, opts []MiddlewareOption) middlewareConfig {
+	cfg := middlewareConfig{
+		sessionHeader: SessionMetadata,
+		manager:       defaultManager,
+		protocol:      KafkaProto,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.node == "" {
+		cfg.node = cfg.protocol
+	}
+	if cfg.cmd == "" {
+		cfg.cmd = topic
+	}
+	return cfg
+}
+
+func (cfg middlewareConfig) messageEvent(invoke, logType, method, topic string, headers map[string]string, payload []byte, d DetailLog) logEvent {
+	limit := bodyLimit(d)
+	length := int64(len(payload))
+	truncated := limit > 0 && len(payload) > limit
+	if truncated {
+		payload = payload[:limit]
+	}
+	return logEvent{
+		node:    cfg.node,
+		cmd:     cfg.cmd,
+		invoke:  invoke,
+		logType: logType,
+		data: MessageData{
+			Topic:   topic,
+			Headers: headers,
+			Payload: bodyValue(messageHeader(headers, ContentType), payload, truncated, length, limit),
+		},
+		protocol:       cfg.protocol,
+		protocolMethod: method,
+	}
+}
+
+// messageHeader returns the value of a header, matching its name case
+// insensitively as brokers do not agree on the case.
+func messageHeader(headers map[string]string, name string) string {
+	if name == "" {
+		return ""
+	}
+	if v, ok := headers[name]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishAndConsumeScope(t *testing.T) {
+	producer, producerSinks := newSinkTestManager(t)
+	consumer, consumerSinks := newSinkTestManager(t)
+
+	// producer side
+	ctx := context.WithValue(context.Background(), xSession, "session_1")
+	trace := NewTraceContext()
+	ctx = WithTraceContext(ctx, trace)
+	detailLog := producer.NewDetailLog("session_1", "", "create_order")
+	ctx = WithDetailLog(ctx, detailLog)
+
+	headers := Publish(ctx, "orders", map[string]string{"Content-Type": ContentTypeJSON}, []byte(`{"id":1}`))
+	detailLog.End()
+
+	assert.Equal(t, "session_1", headers[SessionMetadata])
+	assert.Contains(t, headers[TraceparentHeader], trace.TraceID)
+	assert.NotEmpty(t, headers[XTidMetadata])
+
+	line := detailOutput(t, producerSinks.detail)
+	output := line["Output"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "kafka.orders", output["Event"])
+	assert.Equal(t, "kafka.publish", output["Protocol"])
+	assert.Equal(t, "rep", output["Type"])
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, output["Data"].(map[string]interface{})["payload"])
+
+	// consumer side
+	ctx, end := ConsumeScope(context.Background(), "orders", headers, []byte(`{"id":1}`), WithManager(consumer), WithProtocol(AMQPProto))
+	end(nil)
+
+	assert.Equal(t, "session_1", ctx.Value(xSession))
+	assert.Equal(t, trace.TraceID, ctx.Value(TraceIDKey))
+
+	line = detailOutput(t, consumerSinks.detail)
+	assert.Equal(t, "session_1", line["Session"])
+	assert.Equal(t, trace.TraceID, line["TraceId"])
+	assert.Equal(t, headers[XTidMetadata], line["InitInvoke"])
+	assert.Equal(t, "orders", line["Scenario"])
+	input := line["Input"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "amqp.orders", input["Event"])
+	assert.Equal(t, "amqp.consume", input["Protocol"])
+
+	var summary LogSummaryEntry
+	json.Unmarshal(consumerSinks.summary.Entries()[0].Payload, &summary)
+	assert.Equal(t, "200", summary.ResponseResult)
+	assert.Equal(t, "amqp", summary.Sequences[0].Node)
+}
+
+func TestConsumeScopeError(t *testing.T) {
+	m, sinks := newSinkTestManager(t)
+
+	_, end := ConsumeScope(context.Background(), "orders", nil, []byte("not json"), WithManager(m), WithNode("order_worker"))
+	end(errors.New("stock not found"))
+
+	line := detailOutput(t, sinks.detail)
+	assert.NotEmpty(t, line["Session"])
+	input := line["Input"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "order_worker.orders", input["Event"])
+	assert.Equal(t, "not json", input["Data"].(map[string]interface{})["payload"])
+
+	var summary LogSummaryEntry
+	json.Unmarshal(sinks.summary.Entries()[0].Payload, &summary)
+	assert.Equal(t, "500", summary.ResponseResult)
+	assert.Equal(t, "stock not found", summary.Sequences[0].Result[0].ResultDesc)
+}
+
+func TestConsumePanic(t *testing.T) {
+	m, sinks := newSinkTestManager(t)
+
+	assert.PanicsWithValue(t, "out of stock", func() {
+		Consume(context.Background(), "orders", nil, []byte(`{"id":1}`), func(ctx context.Context) error {
+			panic("out of stock")
+		}, WithManager(m))
+	})
+
+	assert.Len(t, sinks.detail.Entries(), 1)
+	var summary LogSummaryEntry
+	json.Unmarshal(sinks.summary.Entries()[0].Payload, &summary)
+	assert.Equal(t, "500", summary.ResponseResult)
+	assert.Equal(t, "out of stock", summary.Sequences[0].Result[0].ResultDesc)
+}
@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsBuckets are the upper bounds in seconds of the duration histogram.
+var metricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	scenario   string
+	resultCode string
+}
+
+type resultKey struct {
+	scenario   string
+	node       string
+	cmd        string
+	resultCode string
+}
+
+type histogram struct {
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+// summaryMetrics holds the RED metrics derived from the summary logs of a
+// Manager.
+type summaryMetrics struct {
+	mu       sync.Mutex
+	requests map[requestKey]*histogram
+	results  map[resultKey]uint64
+}
+
+func newSummaryMetrics() *summaryMetrics {
+	return &summaryMetrics{
+		requests: map[requestKey]*histogram{},
+		results:  map[resultKey]uint64{},
+	}
+}
+
+// observe records an ended summary log: its result code and duration, and
+// the result code of every node and cmd it called.
+func (sm *summaryMetrics) observe(scenario, resultCode string, elapsed time.Duration, blocks []BlockDetail) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	key := requestKey{scenario: scenario, resultCode: resultCode}
+	h, ok := sm.requests[key]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(metricsBuckets))}
+		sm.requests[key] = h
+	}
+	seconds := elapsed.Seconds()
+	for i, le := range metricsBuckets {
+		if seconds <= le {
+			h.buckets[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+
+	for _, block := range blocks {
+		for _, res := range block.Result {
+			sm.results[resultKey{scenario: scenario, node: block.Node, cmd: block.Cmd, resultCode: res.ResultCode}]++
+		}
+	}
+}
+
+// MetricsHandler returns the metrics handler of the default Manager, see
+// Manager.MetricsHandler.
+func MetricsHandler() http.Handler {
+	return defaultManager.MetricsHandler()
+}
+
+// MetricsHandler returns an http.Handler serving the RED metrics of the
+// summary logs of the Manager in the Prometheus text format:
+//
+//	summary_requests_total             ended summary logs by scenario and result_code
+//	summary_request_duration_seconds   histogram of their process time
+//	summary_node_results_total         results by scenario, node, cmd and result_code
+//
+// Metrics are recorded on End even while the summary stream is turned off.
+func (m *Manager) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ContentType, "text/plain; version=0.0.4; charset=utf-8")
+		m.metrics.writeTo(w)
+	})
+}
+
+func (sm *summaryMetrics) writeTo(w http.ResponseWriter) {
+	sm.mu.Lock()
+	requestKeys := make([]requestKey, 0, len(sm.requests))
+	histograms := make(map[requestKey]histogram, len(sm.requests))
+	for key, h := range sm.requests {
+		requestKeys = append(requestKeys, key)
+		histograms[key] = histogram{buckets: append([]uint64(nil), h.buckets...), sum: h.sum, count: h.count}
+	}
+	resultKeys := make([]resultKey, 0, len(sm.results))
+	results := make(map[resultKey]uint64, len(sm.results))
+	for key, n := range sm.results {
+		resultKeys = append(resultKeys, key)
+		results[key] = n
+	}
+	sm.mu.Unlock()
+
+	sort.Slice(requestKeys, func(i, j int) bool {
+		a, b := requestKeys[i], requestKeys[j]
+		if a.scenario != b.scenario {
+			return a.scenario < b.scenario
+		}
+		return a.resultCode < b.resultCode
+	})
+	sort.Slice(resultKeys, func(i, j int) bool {
+		a, b := resultKeys[i], resultKeys[j]
+		if a.scenario != b.scenario {
+			return a.scenario < b.scenario
+		}
+		if a.node != b.node {
+			return a.node < b.node
+		}
+		if a.cmd != b.cmd {
+			return a.cmd < b.cmd
+		}
+		return a.resultCode < b.resultCode
+	})
+
+	var sb strings.Builder
+	sb.WriteString("# HELP summary_requests_total Ended summary logs.\n")
+	sb.WriteString("# TYPE summary_requests_total counter\n")
+	for _, key := range requestKeys {
+		fmt.Fprintf(&sb, "summary_requests_total%s %d\n", metricLabels("scenario", key.scenario, "result_code", key.resultCode), histograms[key].count)
+	}
+
+	sb.WriteString("# HELP summary_request_duration_seconds Process time of the summary logs.\n")
+	sb.WriteString("# TYPE summary_request_duration_seconds histogram\n")
+	for _, key := range requestKeys {
+		h := histograms[key]
+		for i, le := range metricsBuckets {
+			fmt.Fprintf(&sb, "summary_request_duration_seconds_bucket%s %d\n",
+				metricLabels("scenario", key.scenario, "result_code", key.resultCode, "le", strconv.FormatFloat(le, 'g', -1, 64)), h.buckets[i])
+		}
+		labels := metricLabels("scenario", key.scenario, "result_code", key.resultCode)
+		fmt.Fprintf(&sb, "summary_request_duration_seconds_bucket%s %d\n",
+			metricLabels("scenario", key.scenario, "result_code", key.resultCode, "le", "+Inf"), h.count)
+		fmt.Fprintf(&sb, "summary_request_duration_seconds_sum%s %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&sb, "summary_request_duration_seconds_count%s %d\n", labels, h.count)
+	}
+
+	sb.WriteString("# HELP summary_node_results_total Results of the nodes called by the summary logs.\n")
+	sb.WriteString("# TYPE summary_node_results_total counter\n")
+	for _, key := range resultKeys {
+		fmt.Fprintf(&sb, "summary_node_results_total%s %d\n",
+			metricLabels("scenario", key.scenario, "node", key.node, "cmd", key.cmd, "result_code", key.resultCode), results[key])
+	}
+
+	w.Write([]byte(sb.String()))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricLabels formats name and value pairs as a Prometheus label set.
+func metricLabels(pairs ...string) string {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(pairs[i])
+		sb.WriteString(`="`)
+		sb.WriteString(labelEscaper.Replace(pairs[i+1]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
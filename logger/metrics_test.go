@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func metricsOutput(t *testing.T, m *Manager) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(ContentType), "text/plain; version=0.0.4")
+	return rec.Body.String()
+}
+
+func TestMetricsHandler(t *testing.T) {
+	m, err := New(LogConfig{Sinks: Sinks{Summary: []Sink{NewMemorySink()}}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	for _, code := range []string{"200", "200", "500"} {
+		sl := m.NewSummaryLog("session", "", "create_user")
+		sl.AddSuccess("postgres", "insert", "200", "OK")
+		if code == "500" {
+			sl.AddError("kafka", "publish", "500", "broker down")
+		}
+		sl.End(code, "")
+	}
+
+	// the summary stream switch does not stop the metrics
+	m.SetStreamEnabled(StreamSummary, false)
+	m.NewSummaryLog("session", "", `get_"user"`).End("404", "Not Found")
+
+	out := metricsOutput(t, m)
+	for _, line := range []string{
+		"# TYPE summary_requests_total counter",
+		`summary_requests_total{scenario="create_user",result_code="200"} 2`,
+		`summary_requests_total{scenario="create_user",result_code="500"} 1`,
+		`summary_requests_total{scenario="get_\"user\"",result_code="404"} 1`,
+		"# TYPE summary_request_duration_seconds histogram",
+		`summary_request_duration_seconds_bucket{scenario="create_user",result_code="200",le="10"} 2`,
+		`summary_request_duration_seconds_bucket{scenario="create_user",result_code="200",le="+Inf"} 2`,
+		`summary_request_duration_seconds_count{scenario="create_user",result_code="500"} 1`,
+		"# TYPE summary_node_results_total counter",
+		`summary_node_results_total{scenario="create_user",node="postgres",cmd="insert",result_code="200"} 3`,
+		`summary_node_results_total{scenario="create_user",node="kafka",cmd="publish",result_code="500"} 1`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+
+	// series are sorted
+	assert.Less(t, strings.Index(out, `result_code="200"} 2`), strings.Index(out, `result_code="500"} 1`))
+}
+
+func TestSummaryMetricsBuckets(t *testing.T) {
+	sm := newSummaryMetrics()
+	sm.observe("scenario", "200", 30*time.Millisecond, nil)
+	sm.observe("scenario", "200", 2*time.Second, nil)
+
+	h := sm.requests[requestKey{scenario: "scenario", resultCode: "200"}]
+	assert.Equal(t, []uint64{0, 0, 0, 1, 1, 1, 1, 1, 2, 2, 2}, h.buckets)
+	assert.Equal(t, uint64(2), h.count)
+	assert.InDelta(t, 2.03, h.sum, 1e-9)
+}
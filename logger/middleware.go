@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type MiddlewareOption func(*middlewareConfig)
+
+type middlewareConfig struct {
+	node          string
+	cmd           string
+	sessionHeader string
+	logger        *zap.Logger
+	manager       *Manager
+	protocol      string
+}
+
+// WithNode sets the node name used for the inbound request events (default "client").
+func WithNode(node string) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.node = node
+	}
+}
+
+// WithCmd sets the cmd name used for the inbound request events (default the scenario).
+func WithCmd(cmd string) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.cmd = cmd
+	}
+}
+
+// WithSessionHeader takes the session from the given request header when it is present.
+func WithSessionHeader(header string) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.sessionHeader = header
+	}
+}
+
+// WithLogger binds the session to the given app logger and stores it on the request context.
+func WithLogger(logger *zap.Logger) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.logger = logger
+	}
+}
+
+// WithManager creates the logs with the given Manager instead of the default one.
+func WithManager(m *Manager) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.manager = m
+	}
+}
+
+// WithProtocol sets the protocol of the message helpers, KafkaProto by default.
+func WithProtocol(protocol string) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.protocol = protocol
+	}
+}
+
+// Middleware creates a DetailLog and a SummaryLog for every request, puts them
+// on the request context, records the incoming request and the outgoing
+// response and ends both logs when the handler returns or panics.
+func Middleware(scenario string, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	cfg := middlewareConfig{
+		node:    "client",
+		cmd:     scenario,
+		manager: defaultManager,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, callerInvoke := cfg.manager.extract(r.Context(), r.Header)
+			session := sessionFromRequest(ctx, r.Header, cfg.sessionHeader)
+			ctx = context.WithValue(ctx, xSession, session)
+			debug := IsDebug(ctx) || cfg.manager.Config().Debug.requested(r)
+			if debug {
+				ctx = WithDebug(ctx)
+			}
+			if cfg.logger != nil {
+				ctx, _ = cfg.manager.InitSession(ctx, cfg.logger)
+			}
+
+			invoke := GenerateXTid(cfg.node)
+			initInvoke := invoke
+			if callerInvoke != "" {
+				initInvoke = callerInvoke
+			}
+			detailLog := cfg.manager.NewDetailLog(session, initInvoke, scenario)
+			summaryLog := cfg.manager.NewSummaryLog(session, initInvoke, scenario)
+			if debug {
+				EnableDebug(detailLog)
+			}
+			traceLogs(ctx, detailLog, summaryLog)
+			ctx = WithDetailLog(ctx, detailLog)
+			ctx = WithSummaryLog(ctx, summaryLog)
+			r = r.WithContext(ctx)
+
+			detailLog.AddInputHttpRequest(cfg.node, cfg.cmd, invoke, r, detailLog.IsRawDataEnabled())
+
+			rw := NewResponseRecorder(w, detailLog)
+			defer func() {
+				if rec := recover(); rec != nil {
+					summaryLog.AddError(cfg.node, cfg.cmd, strconv.Itoa(http.StatusInternalServerError), fmt.Sprint(rec))
+					rw.status = http.StatusInternalServerError
+					cfg.end(detailLog, summaryLog, invoke, rw)
+					panic(rec)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			status := rw.StatusCode()
+			if status >= http.StatusBadRequest {
+				summaryLog.AddError(cfg.node, cfg.cmd, strconv.Itoa(status), http.StatusText(status))
+			} else {
+				summaryLog.AddSuccess(cfg.node, cfg.cmd, strconv.Itoa(status), http.StatusText(status))
+			}
+			cfg.end(detailLog, summaryLog, invoke, rw)
+		})
+	}
+}
+
+func (cfg middlewareConfig) end(detailLog DetailLog, summaryLog SummaryLog, invoke string, rw *ResponseRecorder) {
+	status := rw.StatusCode()
+	AddOutputHttpResponse(detailLog, cfg.node, cfg.cmd, invoke, rw, detailLog.IsRawDataEnabled())
+	detailLog.AutoEnd()
+
+	if !summaryLog.IsEnd() {
+		summaryLog.End(strconv.Itoa(status), http.StatusText(status))
+	}
+}
+
+func sessionFromRequest(ctx context.Context, h http.Header, header string) string {
+	if header != "" {
+		if session := h.Get(header); session != "" {
+			return session
+		}
+	}
+
+	if session, ok := ctx.Value(xSession).(string); ok && session != "" {
+		return session
+	}
+	return newSessionID()
+}
+
+func newSessionID() string {
+	uuidV7, err := uuid.NewV7()
+	if err != nil {
+		uuidV7 = uuid.New()
+	}
+	return uuidV7.String()
+}
+
+func parseBody(body []byte) any {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return string(body)
+	}
+	return data
+}
@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		done <- buf.String()
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
+func decodeLines(t *testing.T, out string) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("Failed to decode log line %q: %v", line, err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestMiddleware(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		Detail: DetailLogConfig{
+			RawData:    true,
+			LogConsole: true,
+		},
+		Summary: SummaryLogConfig{
+			LogConsole: true,
+		},
+	}
+
+	var detail DetailLog
+	var summary SummaryLog
+	handler := Middleware("create_user", WithNode("client"), WithSessionHeader("X-Session"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		detail = DetailLogFromContext(r.Context())
+		summary = SummaryLogFromContext(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"name":"john"}`, string(body))
+
+		w.Header().Set(ContentType, ContentTypeJSON)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/users?x=1", strings.NewReader(`{"name":"john"}`))
+	req.Header.Set("X-Session", "test_session")
+	rec := httptest.NewRecorder()
+
+	out := captureStdout(t, func() {
+		handler.ServeHTTP(rec, req)
+	})
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.NotNil(t, detail)
+	assert.NotNil(t, summary)
+	assert.True(t, summary.IsEnd())
+
+	lines := decodeLines(t, out)
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, but got %d: %s", len(lines), out)
+	}
+
+	detailLine, summaryLine := lines[0], lines[1]
+	assert.Equal(t, Detail, detailLine["LogType"])
+	assert.Equal(t, "test_session", detailLine["Session"])
+	assert.Equal(t, "create_user", detailLine["Scenario"])
+
+	output := detailLine["Output"].([]interface{})[0].(map[string]interface{})
+	data := output["Data"].(map[string]interface{})
+	assert.Equal(t, float64(http.StatusCreated), data["statusCode"])
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, data["body"])
+
+	assert.Equal(t, Summary, summaryLine["LogType"])
+	assert.Equal(t, "201", summaryLine["ResponseResult"])
+	assert.Equal(t, "test_session", summaryLine["Session"])
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		Summary: SummaryLogConfig{
+			LogConsole: true,
+		},
+	}
+
+	var summary SummaryLog
+	handler := Middleware("panic")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		summary = SummaryLogFromContext(r.Context())
+		panic("boom")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	rec := httptest.NewRecorder()
+
+	out := captureStdout(t, func() {
+		assert.PanicsWithValue(t, "boom", func() {
+			handler.ServeHTTP(rec, req)
+		})
+	})
+
+	assert.True(t, summary.IsEnd())
+
+	lines := decodeLines(t, out)
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 log line, but got %d: %s", len(lines), out)
+	}
+	assert.Equal(t, "500", lines[0]["ResponseResult"])
+}
+
+func TestMiddlewareHandlerEndsSummary(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+	}
+
+	handler := Middleware("ended")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		summary := SummaryLogFromContext(r.Context())
+		if err := summary.End("20000", "custom"); err != nil {
+			t.Errorf("Expected no error, but got %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
@@ -0,0 +1,394 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OTLPConfig configures the sink created by NewOTLPSink.
+type OTLPConfig struct {
+	// Endpoint is the OTLP/HTTP logs URL, e.g. "http://collector:4318/v1/logs".
+	Endpoint string
+	// Headers are added to every export request, e.g. an API key.
+	Headers map[string]string
+	// ServiceName sets service.name. When empty it is the AppName of the
+	// entry, or the ProjectName of the default configuration.
+	ServiceName string
+	// BatchSize is the number of records sent per request (default 512).
+	BatchSize int
+	// FlushInterval is how often pending records are sent (default 5s).
+	FlushInterval time.Duration
+	// MaxQueueSize bounds the records waiting to be sent, further entries
+	// are dropped and counted in Stats (default 16 batches).
+	MaxQueueSize int
+	// MaxRetries is the number of retries of a failed request (default 3,
+	// none when negative), waiting RetryBackoff (default 500ms) doubled on
+	// every attempt. Only network errors, 429 and 502-504 are retried.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// Gzip compresses the requests.
+	Gzip bool
+	// Client sends the requests, a client with a 10s timeout when nil.
+	Client *http.Client
+}
+
+func (c OTLPConfig) withDefaults() OTLPConfig {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 512
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = 5 * time.Second
+	}
+	if c.MaxQueueSize <= 0 {
+		c.MaxQueueSize = 16 * c.BatchSize
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 500 * time.Millisecond
+	}
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return c
+}
+
+type otlpSink struct {
+	conf    OTLPConfig
+	mu      sync.Mutex
+	pending []Entry
+	closed  bool
+	// exporting serializes exports so records are sent in order
+	exporting sync.Mutex
+	kick      chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewOTLPSink returns a Sink exporting entries as OpenTelemetry log records
+// over OTLP/HTTP with JSON encoding. Records are sent in batches, every
+// FlushInterval or as soon as a batch is full, and on Flush and Close. The
+// resource carries service.name, host.name and process.pid, and records carry
+// the TraceId and SpanId of their entry.
+func NewOTLPSink(conf OTLPConfig) (Sink, error) {
+	if conf.Endpoint == "" {
+		return nil, errors.New("otlp: endpoint is required")
+	}
+	s := &otlpSink{
+		conf:    conf.withDefaults(),
+		kick:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write queues entry for the next batch. Entries that do not fit in the
+// queue are dropped and counted in Stats rather than reported one by one.
+func (s *otlpSink) Write(entry Entry) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errors.New("otlp: sink closed")
+	}
+	if len(s.pending) >= s.conf.MaxQueueSize {
+		s.mu.Unlock()
+		countDropped(entry)
+		return nil
+	}
+	s.pending = append(s.pending, entry)
+	full := len(s.pending) >= s.conf.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *otlpSink) Flush() error {
+	return s.export(true)
+}
+
+func (s *otlpSink) Close() error {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+		close(s.done)
+		<-s.stopped
+	})
+	return s.export(true)
+}
+
+func (s *otlpSink) run() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(s.conf.FlushInterval)
+	defer ticker.Stop()
+	for {
+		partial := true
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		case <-s.kick:
+			partial = false
+		}
+		if err := s.export(partial); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to export OTLP logs: %v\n", err)
+		}
+	}
+}
+
+// export sends the pending records batch by batch, leaving a last partial
+// batch queued unless partial is set.
+func (s *otlpSink) export(partial bool) error {
+	s.exporting.Lock()
+	defer s.exporting.Unlock()
+
+	var errs []error
+	for {
+		s.mu.Lock()
+		n := min(len(s.pending), s.conf.BatchSize)
+		if n < s.conf.BatchSize && !partial {
+			n = 0
+		}
+		batch := append([]Entry(nil), s.pending[:n]...)
+		s.pending = s.pending[n:]
+		s.mu.Unlock()
+		if n == 0 {
+			return errors.Join(errs...)
+		}
+		if err := s.send(batch); err != nil {
+			countDropped(batch...)
+			errs = append(errs, fmt.Errorf("%d records dropped: %w", n, err))
+		}
+	}
+}
+
+func (s *otlpSink) send(batch []Entry) error {
+	body, err := json.Marshal(s.encode(batch))
+	if err != nil {
+		return err
+	}
+	if s.conf.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(body)
+		zw.Close()
+		body = buf.Bytes()
+	}
+
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = s.post(body)
+		if err == nil || !retry || attempt >= s.conf.MaxRetries {
+			return err
+		}
+		time.Sleep(s.conf.RetryBackoff << attempt)
+	}
+}
+
+// post sends one request and reports whether a failure can be retried.
+func (s *otlpSink) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.conf.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set(ContentType, ContentTypeJSON)
+	if s.conf.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range s.conf.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.conf.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+		return true, fmt.Errorf("otlp: collector returned %s", resp.Status)
+	}
+	return false, fmt.Errorf("otlp: collector returned %s", resp.Status)
+}
+
+// The OTLP/JSON encoding of the logs export request. 64-bit integers are
+// strings as in the protobuf JSON mapping.
+type otlpExportRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber,omitempty"`
+	SeverityText         string         `json:"severityText,omitempty"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func otlpString(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+func otlpInt(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{IntValue: &value}}
+}
+
+const otlpScopeName = "github.com/sing3demons/logger-kp"
+
+type otlpResourceKey struct {
+	service, host, pid string
+}
+
+// encode groups the entries of a batch by resource.
+func (s *otlpSink) encode(batch []Entry) otlpExportRequest {
+	host, _ := os.Hostname()
+	pid := strconv.Itoa(os.Getpid())
+	observed := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	var keys []otlpResourceKey
+	records := map[otlpResourceKey][]otlpLogRecord{}
+	for _, entry := range batch {
+		var fields map[string]interface{}
+		json.Unmarshal(entry.Payload, &fields)
+
+		key := otlpResourceKey{
+			service: firstString(s.conf.ServiceName, stringField(fields, "AppName")),
+			host:    firstString(stringField(fields, "Host"), host),
+			pid:     firstString(stringField(fields, "Instance"), pid),
+		}
+		if key.service == "" {
+			key.service = defaultManager.config().ProjectName
+		}
+		if _, ok := records[key]; !ok {
+			keys = append(keys, key)
+		}
+		records[key] = append(records[key], otlpRecord(entry, fields, observed))
+	}
+
+	req := otlpExportRequest{ResourceLogs: make([]otlpResourceLogs, 0, len(keys))}
+	for _, key := range keys {
+		req.ResourceLogs = append(req.ResourceLogs, otlpResourceLogs{
+			Resource: otlpResource{Attributes: []otlpKeyValue{
+				otlpString("service.name", key.service),
+				otlpString("host.name", key.host),
+				otlpInt("process.pid", key.pid),
+			}},
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpScope{Name: otlpScopeName},
+				LogRecords: records[key],
+			}},
+		})
+	}
+	return req
+}
+
+func otlpRecord(entry Entry, fields map[string]interface{}, observed string) otlpLogRecord {
+	body := string(entry.Payload)
+	record := otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(entry.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: observed,
+		SeverityNumber:       9,
+		SeverityText:         "INFO",
+		Body:                 otlpAnyValue{StringValue: &body},
+		Attributes:           []otlpKeyValue{otlpString("log.stream", entry.Stream)},
+		// detail and summary entries use TraceId, app entries traceId
+		TraceID: firstString(stringField(fields, "TraceId"), stringField(fields, "traceId")),
+		SpanID:  firstString(stringField(fields, "SpanId"), stringField(fields, "spanId")),
+	}
+	if entry.Stream == StreamApp {
+		record.SeverityNumber, record.SeverityText = otlpSeverity(stringField(fields, "level"))
+	}
+	if session := firstString(stringField(fields, "Session"), stringField(fields, "session")); session != "" {
+		record.Attributes = append(record.Attributes, otlpString("session", session))
+	}
+	if scenario := stringField(fields, "Scenario"); scenario != "" {
+		record.Attributes = append(record.Attributes, otlpString("scenario", scenario))
+	}
+	return record
+}
+
+// otlpSeverity maps a zap level to the OpenTelemetry severity.
+func otlpSeverity(level string) (int, string) {
+	switch level {
+	case "debug":
+		return 5, "DEBUG"
+	case "warn":
+		return 13, "WARN"
+	case "error":
+		return 17, "ERROR"
+	case "dpanic", "panic", "fatal":
+		return 21, "FATAL"
+	}
+	return 9, "INFO"
+}
+
+func stringField(fields map[string]interface{}, key string) string {
+	s, _ := fields[key].(string)
+	return s
+}
+
+func firstString(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
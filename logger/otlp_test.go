@@ -0,0 +1,236 @@
+package logger
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type otlpCollector struct {
+	mu       sync.Mutex
+	requests []otlpExportRequest
+	headers  []http.Header
+}
+
+func (c *otlpCollector) handler(t *testing.T, status func(attempt int) int) http.Handler {
+	var attempts atomic.Int32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code := status(int(attempts.Add(1))); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("Failed to read gzip body: %v", err)
+				return
+			}
+			body = zr
+		}
+		var req otlpExportRequest
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode export request: %v", err)
+		}
+
+		c.mu.Lock()
+		c.requests = append(c.requests, req)
+		c.headers = append(c.headers, r.Header.Clone())
+		c.mu.Unlock()
+	})
+}
+
+func attributes(kvs []otlpKeyValue) map[string]string {
+	m := map[string]string{}
+	for _, kv := range kvs {
+		switch {
+		case kv.Value.StringValue != nil:
+			m[kv.Key] = *kv.Value.StringValue
+		case kv.Value.IntValue != nil:
+			m[kv.Key] = *kv.Value.IntValue
+		}
+	}
+	return m
+}
+
+func TestOTLPSinkExport(t *testing.T) {
+	collector := &otlpCollector{}
+	server := httptest.NewServer(collector.handler(t, func(int) int { return http.StatusOK }))
+	defer server.Close()
+
+	sink, err := NewOTLPSink(OTLPConfig{
+		Endpoint:      server.URL,
+		Headers:       map[string]string{"X-Api-Key": "secret"},
+		Gzip:          true,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	m, err := New(LogConfig{ProjectName: "test_project", Sinks: Sinks{Detail: []Sink{sink}, Summary: []Sink{sink}}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	trace := NewTraceContext()
+	detailLog := m.NewDetailLog("session_1", "", "create_user")
+	summaryLog := m.NewSummaryLog("session_1", "", "create_user")
+	traceLogs(WithTraceContext(context.Background(), trace), detailLog, summaryLog)
+	detailLog.AddInputRequest("client", "create_user", "invoke", nil, map[string]string{"name": "john"})
+	detailLog.End()
+	summaryLog.End("200", "OK")
+
+	assert.NoError(t, sink.Close())
+	if len(collector.requests) != 1 {
+		t.Fatalf("Expected 1 export request, but got %d", len(collector.requests))
+	}
+	assert.Equal(t, "secret", collector.headers[0].Get("X-Api-Key"))
+	assert.Equal(t, ContentTypeJSON, collector.headers[0].Get(ContentType))
+
+	resourceLogs := collector.requests[0].ResourceLogs
+	if len(resourceLogs) != 1 {
+		t.Fatalf("Expected 1 resource, but got %d", len(resourceLogs))
+	}
+	resource := attributes(resourceLogs[0].Resource.Attributes)
+	assert.Equal(t, "test_project", resource["service.name"])
+	assert.Equal(t, *getInstance(), resource["process.pid"])
+	assert.NotEmpty(t, resource["host.name"])
+
+	records := resourceLogs[0].ScopeLogs[0].LogRecords
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, but got %d", len(records))
+	}
+	for i, stream := range []string{StreamDetail, StreamSummary} {
+		assert.Equal(t, trace.TraceID, records[i].TraceID)
+		assert.Equal(t, trace.SpanID, records[i].SpanID)
+		assert.Equal(t, "INFO", records[i].SeverityText)
+		assert.Equal(t, map[string]string{"log.stream": stream, "session": "session_1", "scenario": "create_user"}, attributes(records[i].Attributes))
+		assert.NotEmpty(t, records[i].TimeUnixNano)
+	}
+	assert.Contains(t, *records[0].Body.StringValue, `"name":"john"`)
+}
+
+func TestOTLPSinkBatching(t *testing.T) {
+	collector := &otlpCollector{}
+	server := httptest.NewServer(collector.handler(t, func(int) int { return http.StatusOK }))
+	defer server.Close()
+
+	sink, err := NewOTLPSink(OTLPConfig{Endpoint: server.URL, ServiceName: "orders", BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer sink.Close()
+
+	for _, level := range []string{"info", "error", "debug"} {
+		sink.Write(Entry{Stream: StreamApp, Time: time.Now(), Payload: []byte(`{"level":"` + level + `","msg":"hello"}`)})
+	}
+
+	// a full batch is sent without waiting for the interval
+	assert.Eventually(t, func() bool {
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		return len(collector.requests) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, sink.Flush())
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if len(collector.requests) != 2 {
+		t.Fatalf("Expected 2 export requests, but got %d", len(collector.requests))
+	}
+	first := collector.requests[0].ResourceLogs[0]
+	assert.Equal(t, "orders", attributes(first.Resource.Attributes)["service.name"])
+	assert.Equal(t, "ERROR", first.ScopeLogs[0].LogRecords[1].SeverityText)
+	assert.Equal(t, "DEBUG", collector.requests[1].ResourceLogs[0].ScopeLogs[0].LogRecords[0].SeverityText)
+}
+
+func TestOTLPSinkRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    func(attempt int) int
+		expectErr bool
+		exported  int
+	}{
+		{
+			name: "Retry unavailable collector",
+			status: func(attempt int) int {
+				if attempt < 3 {
+					return http.StatusServiceUnavailable
+				}
+				return http.StatusOK
+			},
+			exported: 1,
+		},
+		{
+			name:      "Give up after retries",
+			status:    func(int) int { return http.StatusServiceUnavailable },
+			expectErr: true,
+		},
+		{
+			name: "Bad request is not retried",
+			status: func(attempt int) int {
+				if attempt == 1 {
+					return http.StatusBadRequest
+				}
+				return http.StatusOK
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			collector := &otlpCollector{}
+			server := httptest.NewServer(collector.handler(t, tc.status))
+			defer server.Close()
+
+			sink, err := NewOTLPSink(OTLPConfig{Endpoint: server.URL, RetryBackoff: time.Millisecond, FlushInterval: time.Hour})
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			defer sink.Close()
+
+			sink.Write(Entry{Stream: StreamDetail, Time: time.Now(), Payload: []byte(`{"Session":"s"}`)})
+			err = sink.Flush()
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, collector.requests, tc.exported)
+		})
+	}
+}
+
+func TestNewOTLPSinkRequiresEndpoint(t *testing.T) {
+	_, err := NewOTLPSink(OTLPConfig{})
+	assert.Error(t, err)
+}
+
+func TestOTLPSinkQueueFullAndClosed(t *testing.T) {
+	sink, err := NewOTLPSink(OTLPConfig{Endpoint: "http://127.0.0.1:0", BatchSize: 2, MaxQueueSize: 2, FlushInterval: time.Hour, MaxRetries: -1})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	s := sink.(*otlpSink)
+	s.exporting.Lock() // keep the queue full
+
+	dropped := Stats()[StreamDetail].Dropped
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, sink.Write(entryOf("entry")))
+	}
+	assert.Equal(t, dropped+1, Stats()[StreamDetail].Dropped)
+
+	s.exporting.Unlock()
+	sink.Close()
+	assert.Error(t, sink.Write(entryOf("late")))
+}
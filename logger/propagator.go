@@ -0,0 +1,255 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Correlation holds the ids passed from one service to the next.
+type Correlation struct {
+	Session string
+	Invoke  string
+	Trace   TraceContext
+}
+
+// Propagator reads and writes correlation ids in request headers. The
+// Propagator of LogConfig is used by Middleware and InitSessionFromRequest to
+// continue the session, initInvoke and trace of the caller instead of
+// generating them, and by InjectHeaders and NewTransport for outbound calls.
+// When none is set only the W3C trace is continued, the session and
+// initInvoke still come from the session header; set W3CPropagator to take
+// them from the traceparent too.
+type Propagator interface {
+	// Extract returns the ids found in h, ok is false when there are none.
+	Extract(h http.Header) (c Correlation, ok bool)
+	// Inject writes the ids of c to h.
+	Inject(c Correlation, h http.Header)
+}
+
+// W3CPropagator propagates the W3C traceparent and tracestate headers. The
+// trace id is used as the session and the parent span id as the initInvoke.
+type W3CPropagator struct{}
+
+func (W3CPropagator) Extract(h http.Header) (Correlation, bool) {
+	tc, err := ParseTraceparent(h.Get(TraceparentHeader), h.Get(TracestateHeader))
+	if err != nil {
+		return Correlation{}, false
+	}
+	return Correlation{Session: tc.TraceID, Invoke: tc.SpanID, Trace: tc}, true
+}
+
+func (W3CPropagator) Inject(c Correlation, h http.Header) {
+	if c.Trace.TraceID == "" {
+		return
+	}
+	h.Set(TraceparentHeader, c.Trace.Traceparent())
+	if c.Trace.State != "" {
+		h.Set(TracestateHeader, c.Trace.State)
+	}
+}
+
+const (
+	B3Header        = "b3"
+	B3TraceIDHeader = "X-B3-TraceId"
+	B3SpanIDHeader  = "X-B3-SpanId"
+	B3SampledHeader = "X-B3-Sampled"
+	B3FlagsHeader   = "X-B3-Flags"
+)
+
+// B3Propagator propagates Zipkin B3 headers. Both the single b3 header and
+// the X-B3-* headers are extracted; SingleHeader selects the form injected.
+// The trace id is used as the session and the span id as the initInvoke.
+type B3Propagator struct {
+	SingleHeader bool
+}
+
+func (B3Propagator) Extract(h http.Header) (Correlation, bool) {
+	var traceID, spanID, sampled string
+	if single := h.Get(B3Header); single != "" {
+		parts := strings.Split(single, "-")
+		if len(parts) < 2 {
+			// "0", "1" or "d" carry only the sampling decision
+			return Correlation{}, false
+		}
+		traceID, spanID = parts[0], parts[1]
+		if len(parts) > 2 {
+			sampled = parts[2]
+		}
+	} else {
+		traceID, spanID, sampled = h.Get(B3TraceIDHeader), h.Get(B3SpanIDHeader), h.Get(B3SampledHeader)
+		if h.Get(B3FlagsHeader) == "1" {
+			sampled = "d"
+		}
+	}
+
+	traceID, spanID = strings.ToLower(traceID), strings.ToLower(spanID)
+	// 64-bit trace ids are left padded to the 128 bits of W3C
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	if !isHex(traceID, 32) || !isHex(spanID, 16) {
+		return Correlation{}, false
+	}
+
+	tc := TraceContext{TraceID: traceID, SpanID: spanID, Flags: "01"}
+	if sampled == "0" || sampled == "false" {
+		tc.Flags = "00"
+	}
+	return Correlation{Session: traceID, Invoke: spanID, Trace: tc}, true
+}
+
+func (p B3Propagator) Inject(c Correlation, h http.Header) {
+	tc := c.Trace
+	if tc.TraceID == "" {
+		return
+	}
+	sampled := "1"
+	if tc.Flags == "00" {
+		sampled = "0"
+	}
+	if p.SingleHeader {
+		h.Set(B3Header, tc.TraceID+"-"+tc.SpanID+"-"+sampled)
+		return
+	}
+	h.Set(B3TraceIDHeader, tc.TraceID)
+	h.Set(B3SpanIDHeader, tc.SpanID)
+	h.Set(B3SampledHeader, sampled)
+}
+
+// HeaderPropagator propagates the session and invoke in the given headers,
+// e.g. X-Correlation-Id and X-Request-Id. An empty name is skipped.
+type HeaderPropagator struct {
+	Session string
+	Invoke  string
+}
+
+func (p HeaderPropagator) Extract(h http.Header) (Correlation, bool) {
+	var c Correlation
+	if p.Session != "" {
+		c.Session = h.Get(p.Session)
+	}
+	if p.Invoke != "" {
+		c.Invoke = h.Get(p.Invoke)
+	}
+	return c, c.Session != "" || c.Invoke != ""
+}
+
+func (p HeaderPropagator) Inject(c Correlation, h http.Header) {
+	if p.Session != "" && c.Session != "" {
+		h.Set(p.Session, c.Session)
+	}
+	if p.Invoke != "" && c.Invoke != "" {
+		h.Set(p.Invoke, c.Invoke)
+	}
+}
+
+// Propagators combines propagators: ids are extracted from the first one
+// that has them and injected with all of them.
+func Propagators(propagators ...Propagator) Propagator {
+	return compositePropagator(propagators)
+}
+
+type compositePropagator []Propagator
+
+func (ps compositePropagator) Extract(h http.Header) (Correlation, bool) {
+	var c Correlation
+	var found bool
+	for _, p := range ps {
+		next, ok := p.Extract(h)
+		if !ok {
+			continue
+		}
+		found = true
+		if c.Session == "" {
+			c.Session = next.Session
+		}
+		if c.Invoke == "" {
+			c.Invoke = next.Invoke
+		}
+		if c.Trace.TraceID == "" {
+			c.Trace = next.Trace
+		}
+	}
+	return c, found
+}
+
+func (ps compositePropagator) Inject(c Correlation, h http.Header) {
+	for _, p := range ps {
+		p.Inject(c, h)
+	}
+}
+
+// tracePropagator is the default Propagator: the W3C trace of the caller
+// without the session and initInvoke derived from it.
+type tracePropagator struct {
+	W3CPropagator
+}
+
+func (p tracePropagator) Extract(h http.Header) (Correlation, bool) {
+	c, ok := p.W3CPropagator.Extract(h)
+	return Correlation{Trace: c.Trace}, ok
+}
+
+const propagatorKey ContextKey = "propagator"
+
+func (m *Manager) propagator() Propagator {
+	if p := m.config().Propagator; p != nil {
+		return p
+	}
+	return tracePropagator{}
+}
+
+// extract returns ctx with the trace context of an inbound request, a child
+// span of the caller or a new trace, and the session of the caller unless
+// ctx already has one. It also returns the invoke of the caller.
+func (m *Manager) extract(ctx context.Context, h http.Header) (context.Context, string) {
+	p := m.propagator()
+	ctx = context.WithValue(ctx, propagatorKey, p)
+
+	c, _ := p.Extract(h)
+	trace := NewTraceContext()
+	if c.Trace.TraceID != "" {
+		trace = c.Trace.NewSpan()
+	}
+	ctx = WithTraceContext(ctx, trace)
+
+	if session, _ := ctx.Value(xSession).(string); session == "" && c.Session != "" {
+		ctx = context.WithValue(ctx, xSession, c.Session)
+	}
+	return ctx, c.Invoke
+}
+
+func InitSessionFromRequest(r *http.Request, logger *zap.Logger) (context.Context, *zap.Logger) {
+	return defaultManager.InitSessionFromRequest(r, logger)
+}
+
+// InitSessionFromRequest is InitSession for an inbound request: the session
+// and trace of the caller are extracted with the Propagator of the Manager.
+func (m *Manager) InitSessionFromRequest(r *http.Request, logger *zap.Logger) (context.Context, *zap.Logger) {
+	ctx, _ := m.extract(r.Context(), r.Header)
+	return m.InitSession(ctx, logger)
+}
+
+// InjectHeaders writes the session of ctx and a child span of its trace to
+// h for an outbound request, with the Propagator of the Manager that created
+// ctx, or of the default Manager.
+func InjectHeaders(ctx context.Context, h http.Header) {
+	injectHeaders(ctx, h, "")
+}
+
+func injectHeaders(ctx context.Context, h http.Header, invoke string) {
+	p, ok := ctx.Value(propagatorKey).(Propagator)
+	if !ok {
+		p = defaultManager.propagator()
+	}
+
+	c := Correlation{Invoke: invoke}
+	c.Session, _ = ctx.Value(xSession).(string)
+	if tc, ok := TraceContextFromContext(ctx); ok {
+		c.Trace = tc.NewSpan()
+	}
+	p.Inject(c, h)
+}
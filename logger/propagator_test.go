@@ -0,0 +1,204 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestB3PropagatorExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  http.Header
+		ok      bool
+		traceID string
+		flags   string
+	}{
+		{
+			name:    "Single header",
+			header:  http.Header{"B3": {"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90"}},
+			ok:      true,
+			traceID: "80f198ee56343ba864fe8b2a57d3eff7",
+			flags:   "01",
+		},
+		{
+			name:    "Single header with 64-bit trace id, not sampled",
+			header:  http.Header{"B3": {"64fe8b2a57d3eff7-e457b5a2e4d86bd1-0"}},
+			ok:      true,
+			traceID: "000000000000000064fe8b2a57d3eff7",
+			flags:   "00",
+		},
+		{
+			name:   "Sampling decision only",
+			header: http.Header{"B3": {"0"}},
+		},
+		{
+			name: "Multi header",
+			header: http.Header{
+				"X-B3-Traceid": {"80f198ee56343ba864fe8b2a57d3eff7"},
+				"X-B3-Spanid":  {"e457b5a2e4d86bd1"},
+				"X-B3-Sampled": {"1"},
+			},
+			ok:      true,
+			traceID: "80f198ee56343ba864fe8b2a57d3eff7",
+			flags:   "01",
+		},
+		{
+			name:   "Invalid span id",
+			header: http.Header{"X-B3-Traceid": {"80f198ee56343ba864fe8b2a57d3eff7"}, "X-B3-Spanid": {"xyz"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, ok := B3Propagator{}.Extract(tc.header)
+			assert.Equal(t, tc.ok, ok)
+			if !tc.ok {
+				return
+			}
+			assert.Equal(t, tc.traceID, c.Trace.TraceID)
+			assert.Equal(t, "e457b5a2e4d86bd1", c.Trace.SpanID)
+			assert.Equal(t, tc.flags, c.Trace.Flags)
+			assert.Equal(t, tc.traceID, c.Session)
+			assert.Equal(t, "e457b5a2e4d86bd1", c.Invoke)
+		})
+	}
+}
+
+func TestPropagatorInject(t *testing.T) {
+	c := Correlation{
+		Session: "session_1",
+		Invoke:  "invoke_1",
+		Trace:   TraceContext{TraceID: "80f198ee56343ba864fe8b2a57d3eff7", SpanID: "e457b5a2e4d86bd1", Flags: "01"},
+	}
+
+	h := http.Header{}
+	B3Propagator{SingleHeader: true}.Inject(c, h)
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1", h.Get(B3Header))
+
+	h = http.Header{}
+	B3Propagator{}.Inject(c, h)
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", h.Get(B3TraceIDHeader))
+	assert.Equal(t, "e457b5a2e4d86bd1", h.Get(B3SpanIDHeader))
+	assert.Equal(t, "1", h.Get(B3SampledHeader))
+
+	h = http.Header{}
+	W3CPropagator{}.Inject(c, h)
+	assert.Equal(t, "00-80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-01", h.Get(TraceparentHeader))
+
+	h = http.Header{}
+	HeaderPropagator{Session: "X-Correlation-Id", Invoke: "X-Request-Id"}.Inject(c, h)
+	assert.Equal(t, http.Header{"X-Correlation-Id": {"session_1"}, "X-Request-Id": {"invoke_1"}}, h)
+}
+
+func TestPropagatorsExtract(t *testing.T) {
+	p := Propagators(HeaderPropagator{Session: "X-Correlation-Id", Invoke: "X-Request-Id"}, W3CPropagator{})
+	h := http.Header{}
+	h.Set(TraceparentHeader, testTraceparent)
+	h.Set("X-Correlation-Id", "corr_1")
+
+	c, ok := p.Extract(h)
+	assert.True(t, ok)
+	assert.Equal(t, "corr_1", c.Session, "earlier propagators win")
+	assert.Equal(t, "00f067aa0ba902b7", c.Invoke)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", c.Trace.TraceID)
+
+	_, ok = p.Extract(http.Header{})
+	assert.False(t, ok)
+}
+
+func TestMiddlewarePropagator(t *testing.T) {
+	var downstream http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	sink := NewMemorySink()
+	m, err := New(LogConfig{
+		Propagator: Propagators(HeaderPropagator{Session: "X-Correlation-Id", Invoke: "X-Request-Id"}, B3Propagator{}),
+		Sinks:      Sinks{Detail: []Sink{sink}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	client := &http.Client{Transport: NewTransport(nil, "api", "get")}
+	handler := Middleware("get_user", WithManager(m))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, server.URL, nil)
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("X-Correlation-Id", "corr_1")
+	req.Header.Set("X-Request-Id", "req_1")
+	req.Header.Set(B3Header, "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := detailOutput(t, sink)
+	assert.Equal(t, "corr_1", line["Session"])
+	assert.Equal(t, "req_1", line["InitInvoke"])
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", line["TraceId"])
+
+	assert.Equal(t, "corr_1", downstream.Get("X-Correlation-Id"))
+	outbound := line["Output"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, outbound["Invoke"], downstream.Get("X-Request-Id"))
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", downstream.Get(B3TraceIDHeader))
+	assert.Empty(t, downstream.Get(TraceparentHeader))
+}
+
+func TestDefaultPropagator(t *testing.T) {
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		name       string
+		propagator Propagator
+		session    bool
+	}{
+		{
+			name:    "Trace only by default",
+			session: false,
+		},
+		{
+			name:       "Session from the traceparent with W3CPropagator",
+			propagator: W3CPropagator{},
+			session:    true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := New(LogConfig{Propagator: tc.propagator})
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(TraceparentHeader, testTraceparent)
+			ctx, _ := m.InitSessionFromRequest(req, nil)
+			assert.Equal(t, traceID, ctx.Value(TraceIDKey))
+			assert.Equal(t, tc.session, ctx.Value(xSession) == traceID)
+		})
+	}
+}
+
+func TestInitSessionFromRequest(t *testing.T) {
+	m, err := New(LogConfig{Propagator: B3Propagator{}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(B3Header, "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1")
+	ctx, _ := m.InitSessionFromRequest(req, nil)
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", ctx.Value(xSession))
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", ctx.Value(TraceIDKey))
+
+	h := http.Header{}
+	InjectHeaders(ctx, h)
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", h.Get(B3TraceIDHeader))
+	assert.NotEqual(t, "e457b5a2e4d86bd1", h.Get(B3SpanIDHeader))
+}
@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+)
+
+// ResponseRecorder wraps an http.ResponseWriter and records the status,
+// headers, byte count and body of the response for AddOutputHttpResponse.
+// The body is kept up to the MaxBodyBytes of the DetailLog it was created
+// for; everything is still written to the client. Flush, Hijack and Push are
+// passed through to the wrapped writer.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	size   int64
+	limit  int
+}
+
+// NewResponseRecorder wraps w, keeping the body up to the limit of d.
+func NewResponseRecorder(w http.ResponseWriter, d DetailLog) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w, limit: bodyLimit(d)}
+}
+
+func (rw *ResponseRecorder) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *ResponseRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	// keep no more than limit bytes of the body for the log
+	keep := b[:n]
+	if rw.limit > 0 && rw.body.Len()+len(keep) > rw.limit {
+		keep = keep[:max(rw.limit-rw.body.Len(), 0)]
+	}
+	rw.body.Write(keep)
+	rw.size += int64(n)
+	return n, err
+}
+
+func (rw *ResponseRecorder) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection over to the caller; the response is then
+// recorded as written so far.
+func (rw *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := rw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (rw *ResponseRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (rw *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// StatusCode returns the status sent, http.StatusOK when none was set.
+func (rw *ResponseRecorder) StatusCode() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
+
+// Size returns the number of body bytes written.
+func (rw *ResponseRecorder) Size() int64 {
+	return rw.size
+}
+
+// Body returns the recorded body, cut at the body limit.
+func (rw *ResponseRecorder) Body() []byte {
+	return rw.body.Bytes()
+}
+
+func (rw *ResponseRecorder) outGoing() OutGoing {
+	size := rw.Size()
+	return OutGoing{
+		StatusCode: rw.StatusCode(),
+		Header:     rw.Header(),
+		Body:       bodyValue(rw.Header().Get(ContentType), rw.body.Bytes(), rw.size > int64(rw.body.Len()), rw.size, rw.limit),
+		Size:       &size,
+	}
+}
+
+// AddOutputHttpResponse records on d the response written through rw:
+// status, headers and the body decoded by its Content-Type.
+func AddOutputHttpResponse(d DetailLog, node, cmd, invoke string, rw *ResponseRecorder, rawData bool) {
+	data := rw.outGoing()
+
+	var raw interface{}
+	if rawData {
+		raw = data
+	}
+	d.AddOutputResponse(node, cmd, invoke, raw, data)
+}
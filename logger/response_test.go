@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddOutputHttpResponse(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{
+		Detail: DetailLogConfig{MaxBodyBytes: 16},
+		Sinks:  Sinks{Detail: []Sink{sink}},
+	}, WithDetailRawData(false))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	dl := m.NewDetailLog("session", "invoke", "scenario")
+	rec := httptest.NewRecorder()
+	rw := NewResponseRecorder(rec, dl)
+	rw.Header().Set(ContentType, "text/plain")
+	rw.WriteHeader(http.StatusCreated)
+	rw.Write([]byte("hello "))
+	rw.Write([]byte("world, this is long"))
+
+	assert.Equal(t, http.StatusCreated, rw.StatusCode())
+	assert.Equal(t, int64(25), rw.Size())
+	assert.Equal(t, "hello world, thi", string(rw.Body()))
+	assert.Equal(t, "hello world, this is long", rec.Body.String())
+
+	AddOutputHttpResponse(dl, "client", "create", "invoke", rw, false)
+	dl.End()
+
+	line := detailOutput(t, sink)
+	output := line["Output"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "res", output["Type"])
+	data := output["Data"].(map[string]interface{})
+	assert.Equal(t, float64(http.StatusCreated), data["statusCode"])
+	assert.Equal(t, float64(25), data["size"])
+	assert.Equal(t, map[string]interface{}{ContentType: []interface{}{"text/plain"}}, data["header"])
+	assert.Equal(t, map[string]interface{}{
+		"truncated": true,
+		"length":    float64(25),
+		"preview":   "hello world, thi",
+	}, data["body"])
+}
+
+func TestAddOutputHttpResponseMock(t *testing.T) {
+	mockLog := new(MockDetailLog)
+	rw := NewResponseRecorder(httptest.NewRecorder(), mockLog)
+	rw.WriteHeader(http.StatusNoContent)
+
+	mockLog.On("AddOutputResponse", "client", "create", "invoke", nil, rw.outGoing()).Return()
+	AddOutputHttpResponse(mockLog, "client", "create", "invoke", rw, false)
+	mockLog.AssertExpectations(t)
+}
+
+func TestResponseRecorderPassthrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := NewResponseRecorder(rec, noopDetailLog{})
+
+	// httptest.ResponseRecorder flushes but cannot hijack or push
+	assert.NoError(t, http.NewResponseController(rw).Flush())
+	assert.True(t, rec.Flushed)
+	_, _, err := rw.Hijack()
+	assert.ErrorIs(t, err, http.ErrNotSupported)
+	assert.ErrorIs(t, rw.Push("/style.css", nil), http.ErrNotSupported)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := NewResponseRecorder(w, noopDetailLog{}).Hijack()
+		if err != nil {
+			t.Errorf("Expected no error, but got %v", err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+		buf.Flush()
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := bufio.NewReader(resp.Body).ReadString('\n')
+	assert.Equal(t, "ok", body)
+}
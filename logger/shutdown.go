@@ -0,0 +1,270 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// ShutdownConfig sets the result used to end the summary logs that are still
+// open when Shutdown is called.
+type ShutdownConfig struct {
+	ResultCode string `json:"resultCode"`
+	ResultDesc string `json:"resultDesc"`
+}
+
+const (
+	defaultShutdownResultCode = "shutdown"
+	defaultShutdownResultDesc = "process shutdown before the transaction ended"
+)
+
+// openLogSet holds the detail and summary logs of a Manager that have not
+// been ended yet, so Shutdown can end them.
+type openLogSet struct {
+	sync.Mutex
+	detail  map[*detailLog]struct{}
+	summary map[*summaryLog]struct{}
+}
+
+type logFile struct {
+	log    *zap.Logger
+	writer *lumberjack.Logger
+}
+
+var logFiles = struct {
+	sync.Mutex
+	files []logFile
+}{}
+
+func (s *openLogSet) trackDetail(dl *detailLog) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	if s.detail == nil {
+		s.detail = map[*detailLog]struct{}{}
+	}
+	s.detail[dl] = struct{}{}
+	s.Unlock()
+}
+
+func (s *openLogSet) untrackDetail(dl *detailLog) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	delete(s.detail, dl)
+	s.Unlock()
+}
+
+func (s *openLogSet) trackSummary(sl *summaryLog) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	if s.summary == nil {
+		s.summary = map[*summaryLog]struct{}{}
+	}
+	s.summary[sl] = struct{}{}
+	s.Unlock()
+}
+
+func (s *openLogSet) untrackSummary(sl *summaryLog) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	delete(s.summary, sl)
+	s.Unlock()
+}
+
+func trackLogFile(log *zap.Logger, writer *lumberjack.Logger) {
+	logFiles.Lock()
+	logFiles.files = append(logFiles.files, logFile{log: log, writer: writer})
+	logFiles.Unlock()
+}
+
+// end ends every detail and summary log of the set.
+func (s *openLogSet) end() {
+	s.Lock()
+	details := make([]*detailLog, 0, len(s.detail))
+	for dl := range s.detail {
+		details = append(details, dl)
+	}
+	summaries := make([]*summaryLog, 0, len(s.summary))
+	for sl := range s.summary {
+		summaries = append(summaries, sl)
+	}
+	s.detail = nil
+	s.summary = nil
+	s.Unlock()
+
+	for _, dl := range details {
+		dl.forceEnd()
+	}
+
+	for _, sl := range summaries {
+		code := sl.conf.Shutdown.ResultCode
+		if code == "" {
+			code = defaultShutdownResultCode
+		}
+		desc := sl.conf.Shutdown.ResultDesc
+		if desc == "" {
+			desc = defaultShutdownResultDesc
+		}
+		sl.End(code, desc)
+	}
+}
+
+func runningManagers() []*Manager {
+	managers.Lock()
+	defer managers.Unlock()
+	return append([]*Manager(nil), managers.list...)
+}
+
+// configuredSinks returns the sinks configured on every Manager.
+func configuredSinks() []Sink {
+	var sinks []Sink
+	for _, m := range runningManagers() {
+		sinks = appendSinks(sinks, m.sinks()...)
+	}
+	return sinks
+}
+
+func (m *Manager) sinks() []Sink {
+	conf := m.config()
+	var sinks []Sink
+	sinks = appendSinks(sinks, conf.Sinks.App...)
+	sinks = appendSinks(sinks, conf.Sinks.Detail...)
+	return appendSinks(sinks, conf.Sinks.Summary...)
+}
+
+// Flush blocks until every buffered detail and summary entry has been
+// written and the sinks of the Manager have been flushed, or ctx is done.
+func (m *Manager) Flush(ctx context.Context) error {
+	errs := []error{flushAsync(ctx)}
+	for _, sink := range m.sinks() {
+		errs = append(errs, sink.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown ends the detail and summary logs of the Manager still in flight,
+// drains and stops its async pipelines and closes its sinks. The Manager is
+// no longer reached by the package level Flush and Shutdown afterwards, so
+// call it on a Manager that is discarded before the process exits.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.open.end()
+
+	sinks, err := closeAsync(ctx, m.config().Async)
+	errs := []error{err}
+	for _, sink := range appendSinks(sinks, m.sinks()...) {
+		errs = append(errs, sink.Close())
+	}
+
+	if m != defaultManager {
+		managers.Lock()
+		for i, other := range managers.list {
+			if other == m {
+				managers.list = append(managers.list[:i], managers.list[i+1:]...)
+				break
+			}
+		}
+		managers.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Flush blocks until every buffered detail and summary entry has been
+// written and the sinks of every Manager and the log files have been
+// flushed, or ctx is done.
+func Flush(ctx context.Context) error {
+	errs := []error{flushAsync(ctx)}
+	for _, sink := range configuredSinks() {
+		errs = append(errs, sink.Flush())
+	}
+
+	logFiles.Lock()
+	for _, f := range logFiles.files {
+		errs = append(errs, f.log.Sync())
+	}
+	logFiles.Unlock()
+	return errors.Join(errs...)
+}
+
+// Shutdown ends the detail and summary logs still in flight, drains the
+// async pipelines, closes the sinks of every Manager and syncs and closes
+// the log files.
+// Entries written afterwards are written synchronously.
+func Shutdown(ctx context.Context) error {
+	for _, m := range runningManagers() {
+		m.open.end()
+	}
+
+	sinks, err := shutdownAsync(ctx)
+	errs := []error{err}
+
+	sinks = appendSinks(sinks, configuredSinks()...)
+	for _, sink := range sinks {
+		errs = append(errs, sink.Close())
+	}
+
+	logFiles.Lock()
+	files := logFiles.files
+	logFiles.files = nil
+	logFiles.Unlock()
+
+	for _, f := range files {
+		errs = append(errs, f.log.Sync(), f.writer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// ShutdownOnSignal runs Shutdown when one of the signals (SIGTERM and
+// os.Interrupt by default) is received. Once done, the signal is raised
+// again so the process terminates as it would have without the hook, unless
+// the application is listening for it too. The returned func removes the hook.
+func ShutdownOnSignal(timeout time.Duration, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	done := make(chan struct{})
+	go shutdownOnSignal(ch, done, timeout, func(sig os.Signal) {
+		signal.Stop(ch)
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			if err := p.Signal(sig); err != nil {
+				os.Exit(1)
+			}
+		}
+	})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+func shutdownOnSignal(ch <-chan os.Signal, done <-chan struct{}, timeout time.Duration, raise func(os.Signal)) {
+	select {
+	case <-done:
+		return
+	case sig := <-ch:
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		Shutdown(ctx)
+		raise(sig)
+	}
+}
@@ -0,0 +1,228 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownEndsOpenLogs(t *testing.T) {
+	detailSink := NewMemorySink()
+	summarySink := NewMemorySink()
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		Shutdown: ShutdownConfig{
+			ResultCode: "50300",
+		},
+		Sinks: Sinks{
+			Detail:  []Sink{detailSink},
+			Summary: []Sink{summarySink},
+		},
+	}
+
+	ended := NewSummaryLog("ended_session", "test_invoke", "test_cmd")
+	ended.End("200", "OK")
+
+	open := NewSummaryLog("open_session", "test_invoke", "test_cmd")
+	dl := NewDetailLog("open_session", "test_invoke", "test_scenario")
+	dl.AddInputRequest("test_node", "test_cmd", "test_invoke", nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, Shutdown(ctx))
+
+	assert.True(t, open.IsEnd())
+	assert.Len(t, detailSink.Entries(), 1)
+
+	entries := summarySink.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 summary entries, but got %d", len(entries))
+	}
+
+	var summary LogSummaryEntry
+	if err := json.Unmarshal(entries[1].Payload, &summary); err != nil {
+		t.Fatalf("Failed to decode summary entry: %v", err)
+	}
+	assert.Equal(t, "open_session", summary.Session)
+	assert.Equal(t, "50300", summary.ResponseResult)
+	assert.Equal(t, defaultShutdownResultDesc, summary.ResponseDesc)
+
+	// nothing is left to end on a second call
+	assert.NoError(t, Shutdown(ctx))
+	assert.Len(t, summarySink.Entries(), 2)
+}
+
+func TestShutdownWithRunningHandler(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{Sinks: Sinks{Detail: []Sink{sink}}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	idle := m.NewDetailLog("idle_session", "invoke", "scenario")
+	idle.AddInputRequest("client", "cmd", "invoke", nil, nil)
+	dl := m.NewDetailLog("session", "invoke", "scenario")
+	dl.AddInputRequest("client", "cmd", "invoke", nil, map[string]string{"id": "1"})
+
+	done := make(chan struct{})
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			dl.AddOutputRequest("db", "query", "", nil, map[string]int{"i": i})
+		}
+		<-shutdown
+		dl.End()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, Shutdown(ctx))
+	close(shutdown)
+	<-done
+
+	written := len(sink.Entries())
+	idle.End()
+	assert.Len(t, sink.Entries(), written, "End after Shutdown is a no-op")
+
+	// the handler's End after Shutdown only writes what was added after the
+	// forced end, without failing
+	outputs := 0
+	for _, entry := range sink.Entries() {
+		var line detailLog
+		if err := json.Unmarshal(entry.Payload, &line); err != nil {
+			t.Fatalf("Failed to decode detail entry: %v", err)
+		}
+		if line.Session == "session" {
+			outputs += len(line.Output)
+		}
+	}
+	assert.Equal(t, 100, outputs)
+
+	// the log can be reused afterwards
+	written = len(sink.Entries())
+	dl.AddInputRequest("client", "cmd", "invoke", nil, nil)
+	dl.End()
+	assert.Len(t, sink.Entries(), written+1)
+}
+
+// closeSink counts the calls to Flush and Close.
+type closeSink struct {
+	MemorySink
+	flushed atomic.Int32
+	closed  atomic.Int32
+}
+
+func (s *closeSink) Flush() error {
+	s.flushed.Add(1)
+	return nil
+}
+
+func (s *closeSink) Close() error {
+	s.closed.Add(1)
+	return nil
+}
+
+func TestShutdownClosesManagerSinks(t *testing.T) {
+	sink := &closeSink{}
+	m, err := New(LogConfig{Sinks: Sinks{Detail: []Sink{sink}, Summary: []Sink{sink}}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	m.NewSummaryLog("session", "invoke", "scenario").End("200", "OK")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, Flush(ctx))
+	assert.Equal(t, int32(1), sink.flushed.Load())
+
+	assert.NoError(t, Shutdown(ctx))
+	assert.Equal(t, int32(1), sink.closed.Load())
+}
+
+func TestManagerShutdown(t *testing.T) {
+	sink1, sink2 := NewMemorySink(), NewMemorySink()
+	m1, err := New(LogConfig{Sinks: Sinks{Detail: []Sink{sink1}}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	m2, err := New(LogConfig{Sinks: Sinks{Detail: []Sink{sink2}}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	m1.NewDetailLog("session", "invoke", "scenario").AddInputRequest("client", "cmd", "invoke", nil, nil)
+	m2.NewDetailLog("session", "invoke", "scenario").AddInputRequest("client", "cmd", "invoke", nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, m1.Shutdown(ctx))
+	assert.Len(t, sink1.Entries(), 1)
+	assert.Empty(t, sink2.Entries(), "the logs of other Managers stay open")
+	assert.NotContains(t, runningManagers(), m1)
+	assert.Contains(t, runningManagers(), m2)
+
+	assert.NoError(t, Shutdown(ctx))
+	assert.Len(t, sink2.Entries(), 1)
+	m2.Shutdown(ctx)
+}
+
+func TestShutdownClosesLogFiles(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+	}
+
+	dir := t.TempDir()
+	sink, err := NewFileSink(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	sink.Write(Entry{Stream: StreamDetail, Payload: []byte(`{"key":"value"}`)})
+
+	logFiles.Lock()
+	count := len(logFiles.files)
+	logFiles.Unlock()
+	assert.NotZero(t, count)
+
+	assert.NoError(t, Flush(context.Background()))
+	assert.NoError(t, Shutdown(context.Background()))
+
+	logFiles.Lock()
+	defer logFiles.Unlock()
+	assert.Empty(t, logFiles.files)
+}
+
+func TestShutdownOnSignal(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+	}
+	sl := NewSummaryLog("test_session", "test_invoke", "test_cmd")
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	raised := make(chan os.Signal, 1)
+	go shutdownOnSignal(ch, done, time.Second, func(sig os.Signal) {
+		raised <- sig
+	})
+
+	ch <- syscall.SIGTERM
+	select {
+	case sig := <-raised:
+		assert.Equal(t, syscall.SIGTERM, sig)
+	case <-time.After(time.Second):
+		t.Fatal("Expected the signal to be raised again after shutdown")
+	}
+	assert.True(t, sl.IsEnd())
+}
+
+func TestShutdownOnSignalStop(t *testing.T) {
+	stop := ShutdownOnSignal(time.Second)
+	stop()
+	stop()
+}
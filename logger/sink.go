@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	StreamApp     = "app"
+	StreamDetail  = "detail"
+	StreamSummary = "summary"
+)
+
+// Entry is a single serialized log line handed to a Sink.
+type Entry struct {
+	Stream  string
+	Time    time.Time
+	Payload []byte
+}
+
+// Sink is a destination for app, detail and summary entries.
+type Sink interface {
+	Write(entry Entry) error
+	Flush() error
+	Close() error
+}
+
+// Sinks holds the custom sinks registered for each stream. They are used in
+// addition to the console and file outputs enabled by the LogConsole and
+// LogFile options.
+type Sinks struct {
+	App     []Sink
+	Detail  []Sink
+	Summary []Sink
+}
+
+type stdoutSink struct{}
+
+// NewStdoutSink returns a Sink writing one entry per line to os.Stdout.
+func NewStdoutSink() Sink {
+	return stdoutSink{}
+}
+
+func (stdoutSink) Write(entry Entry) error {
+	line := make([]byte, 0, len(entry.Payload)+2)
+	line = append(line, entry.Payload...)
+	_, err := os.Stdout.Write(append(line, endOfLine()...))
+	return err
+}
+
+func (stdoutSink) Flush() error {
+	return nil
+}
+
+func (stdoutSink) Close() error {
+	return nil
+}
+
+// fileSink writes to a rotated log file. It is a comparable value so that
+// the sinks resolved for the same file on every entry are deduplicated.
+type fileSink struct {
+	log *zap.Logger
+}
+
+// NewFileSink returns a Sink writing to a rotated log file inside dir.
+func NewFileSink(dir string) (Sink, error) {
+	if err := ensureLogDirExists(dir); err != nil {
+		return nil, err
+	}
+	log, err := createLogger(configLog.ProjectName, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{log: log}, nil
+}
+
+func (s fileSink) Write(entry Entry) error {
+	s.log.Info(string(entry.Payload))
+	return nil
+}
+
+func (s fileSink) Flush() error {
+	return s.log.Sync()
+}
+
+func (s fileSink) Close() error {
+	return s.log.Sync()
+}
+
+// MemorySink keeps every entry in memory. It is mostly useful in tests.
+type MemorySink struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (s *MemorySink) Write(entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *MemorySink) Flush() error {
+	return nil
+}
+
+func (s *MemorySink) Close() error {
+	return nil
+}
+
+// Entries returns a copy of the entries written so far.
+func (s *MemorySink) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Entry(nil), s.entries...)
+}
+
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = nil
+}
+
+// streamSinks resolves the outputs of a stream from its console/file options
+// followed by the custom sinks.
+func streamSinks(logConsole, logFile bool, file *zap.Logger, custom []Sink) []Sink {
+	sinks := make([]Sink, 0, len(custom)+2)
+	if logConsole {
+		sinks = append(sinks, stdoutSink{})
+	}
+	if logFile && file != nil {
+		sinks = append(sinks, fileSink{log: file})
+	}
+	return append(sinks, custom...)
+}
+
+func writeEntry(sinks []Sink, entry Entry) {
+	if c := streamCounters[entry.Stream]; c != nil {
+		c.written.Add(1)
+	}
+	for _, sink := range sinks {
+		if err := sink.Write(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write %s log: %v\n", entry.Stream, err)
+		}
+	}
+}
+
+// sinkWriter adapts a Sink to a zapcore.WriteSyncer for the app logger.
+type sinkWriter struct {
+	sink   Sink
+	stream string
+}
+
+func (w sinkWriter) Write(p []byte) (int, error) {
+	payload := bytes.TrimRight(p, "\r\n")
+	err := w.sink.Write(Entry{
+		Stream:  w.stream,
+		Time:    time.Now(),
+		Payload: append([]byte(nil), payload...),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w sinkWriter) Sync() error {
+	return w.sink.Flush()
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorySinkDetailAndSummary(t *testing.T) {
+	detailSink := NewMemorySink()
+	summarySink := NewMemorySink()
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		Sinks: Sinks{
+			Detail:  []Sink{detailSink},
+			Summary: []Sink{summarySink},
+		},
+	}
+
+	dl := NewDetailLog("test_session", "test_invoke", "test_scenario")
+	dl.AddInputRequest("test_node", "test_cmd", "test_invoke", nil, map[string]interface{}{"key": "value"})
+	dl.End()
+
+	sl := NewSummaryLog("test_session", "test_invoke", "test_cmd")
+	sl.AddSuccess("test_node", "test_cmd", "200", "OK")
+	sl.End("200", "OK")
+
+	detailEntries := detailSink.Entries()
+	if len(detailEntries) != 1 {
+		t.Fatalf("Expected 1 detail entry, but got %d", len(detailEntries))
+	}
+	assert.Equal(t, StreamDetail, detailEntries[0].Stream)
+
+	var detail map[string]interface{}
+	if err := json.Unmarshal(detailEntries[0].Payload, &detail); err != nil {
+		t.Fatalf("Failed to decode detail entry: %v", err)
+	}
+	assert.Equal(t, "test_session", detail["Session"])
+
+	summaryEntries := summarySink.Entries()
+	if len(summaryEntries) != 1 {
+		t.Fatalf("Expected 1 summary entry, but got %d", len(summaryEntries))
+	}
+	assert.Equal(t, StreamSummary, summaryEntries[0].Stream)
+
+	var summary LogSummaryEntry
+	if err := json.Unmarshal(summaryEntries[0].Payload, &summary); err != nil {
+		t.Fatalf("Failed to decode summary entry: %v", err)
+	}
+	assert.Equal(t, "200", summary.ResponseResult)
+
+	detailSink.Reset()
+	assert.Empty(t, detailSink.Entries())
+}
+
+func TestMemorySinkAppLog(t *testing.T) {
+	appSink := NewMemorySink()
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		AppLog: AppLog{
+			LogConsole: true,
+		},
+		Sinks: Sinks{
+			App: []Sink{appSink},
+		},
+	}
+
+	log := NewLogger()
+	log.Info("hello")
+
+	entries := appSink.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 app entry, but got %d", len(entries))
+	}
+	assert.Equal(t, StreamApp, entries[0].Stream)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(entries[0].Payload, &line); err != nil {
+		t.Fatalf("Failed to decode app entry: %v", err)
+	}
+	assert.Equal(t, "hello", line["msg"])
+	assert.Equal(t, "info", line["level"])
+	if _, err := time.Parse(time.RFC3339Nano, line["time"].(string)); err != nil {
+		t.Errorf("Expected an RFC3339 time, but got %v", line["time"])
+	}
+}
+
+func TestStdoutSink(t *testing.T) {
+	out := captureStdout(t, func() {
+		NewStdoutSink().Write(Entry{Stream: StreamDetail, Payload: []byte(`{"key":"value"}`)})
+	})
+	assert.Equal(t, `{"key":"value"}`+endOfLine(), out)
+}
+
+func TestFileSink(t *testing.T) {
+	dir := t.TempDir() + "/sink_logs"
+	sink, err := NewFileSink(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	assert.NoError(t, sink.Write(Entry{Stream: StreamSummary, Payload: []byte(`{"key":"value"}`)}))
+	sink.Flush()
+	assert.NoError(t, sink.Close())
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 log file, but got %d", len(files))
+	}
+}
+
+func TestStreamSinks(t *testing.T) {
+	custom := NewMemorySink()
+
+	assert.Len(t, streamSinks(false, false, nil, nil), 0)
+	assert.Len(t, streamSinks(true, false, nil, nil), 1)
+	assert.Len(t, streamSinks(false, true, nil, nil), 0, "file output without a logger is skipped")
+	assert.Len(t, streamSinks(true, false, nil, []Sink{custom}), 2)
+}
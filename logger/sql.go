@@ -0,0 +1,349 @@
+package logger
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLProto is the protocol of the database entries in the detail log.
+const SQLProto = "sql"
+
+// SQLData is the data of a database entry in the detail log. The request
+// carries the statement and its arguments, keyed by name or by position
+// ("1", "2", ...) so they can be masked with DetailLogConfig.Mask, e.g. the
+// field rule "$.args.2". The response carries the rows affected, the
+// duration and the error.
+type SQLData struct {
+	Statement    string         `json:"statement,omitempty"`
+	Args         map[string]any `json:"args,omitempty"`
+	RowsAffected *int64         `json:"rowsAffected,omitempty"`
+	Duration     string         `json:"duration,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// WrapDriver wraps a database/sql driver so every query and exec run with a
+// context is recorded on the DetailLog and SummaryLog of the context, with
+// node as the node name and the SQL verb (select, insert, ...) as the cmd.
+// Register the result with sql.Register, or use OpenDB.
+func WrapDriver(node string, d driver.Driver) driver.Driver {
+	return &sqlDriver{node: node, base: d}
+}
+
+// OpenDB opens a database whose connections come from c and are recorded as
+// WrapDriver does.
+func OpenDB(node string, c driver.Connector) *sql.DB {
+	return sql.OpenDB(&sqlConnector{
+		base:   c,
+		driver: &sqlDriver{node: node, base: c.Driver()},
+	})
+}
+
+type sqlDriver struct {
+	node string
+	base driver.Driver
+}
+
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{node: d.node, base: conn}, nil
+}
+
+func (d *sqlDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.base.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &sqlConnector{base: c, driver: d}, nil
+	}
+	return &sqlConnector{base: dsnConnector{name: name, driver: d.base}, driver: d}, nil
+}
+
+type sqlConnector struct {
+	base   driver.Connector
+	driver *sqlDriver
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{node: c.driver.node, base: conn}, nil
+}
+
+func (c *sqlConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type dsnConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type sqlConn struct {
+	node string
+	base driver.Conn
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.base.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.base.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStmt{node: c.node, query: query, base: stmt}, nil
+}
+
+func (c *sqlConn) Close() error {
+	return c.base.Close()
+}
+
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx falls back to Begin like database/sql does for drivers without
+// ConnBeginTx, refusing the options Begin cannot honour.
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.base.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	return c.base.Begin()
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.base.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := e.ExecContext(ctx, query, args)
+	recordSQL(ctx, c.node, "exec", query, args, start, result, err)
+	return result, err
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.base.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	recordSQL(ctx, c.node, "query", query, args, start, nil, err)
+	return rows, err
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := c.base.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.base.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) IsValid() bool {
+	if v, ok := c.base.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.base.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type sqlStmt struct {
+	node  string
+	query string
+	base  driver.Stmt
+}
+
+func (s *sqlStmt) Close() error {
+	return s.base.Close()
+}
+
+func (s *sqlStmt) NumInput() int {
+	return s.base.NumInput()
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamed(args))
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamed(args))
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if e, ok := s.base.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else if values, verr := namedToValues(args); verr != nil {
+		return nil, verr
+	} else {
+		result, err = s.base.Exec(values)
+	}
+	recordSQL(ctx, s.node, "exec", s.query, args, start, result, err)
+	return result, err
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.base.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else if values, verr := namedToValues(args); verr != nil {
+		return nil, verr
+	} else {
+		rows, err = s.base.Query(values)
+	}
+	recordSQL(ctx, s.node, "query", s.query, args, start, nil, err)
+	return rows, err
+}
+
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.base.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func valuesToNamed(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+func namedToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sql: driver does not support named arguments")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// recordSQL adds an operation to the logs of ctx: a request and a response
+// entry on the DetailLog and a block on the SummaryLog.
+func recordSQL(ctx context.Context, node, method, query string, args []driver.NamedValue, start time.Time, result driver.Result, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	detailLog := DetailLogFromContext(ctx)
+	summaryLog := SummaryLogFromContext(ctx)
+	if _, ok := detailLog.(noopDetailLog); ok {
+		if _, ok := summaryLog.(noopSummaryLog); ok {
+			return
+		}
+	}
+
+	cmd := sqlVerb(query, method)
+	invoke := GenerateXTid(node)
+	event := logEvent{node: node, cmd: cmd, invoke: invoke, protocol: SQLProto, protocolMethod: method}
+
+	req := event
+	req.logType = "rep"
+	req.start = start
+	req.data = SQLData{Statement: query, Args: sqlArgs(args)}
+	addOutputEvent(detailLog, req)
+
+	resData := SQLData{Duration: fmt.Sprintf("%d ms", time.Since(start).Milliseconds())}
+	if result != nil && err == nil {
+		if n, rerr := result.RowsAffected(); rerr == nil {
+			resData.RowsAffected = &n
+		}
+	}
+	if err != nil {
+		resData.Error = err.Error()
+	}
+	res := event
+	res.logType = "res"
+	res.data = resData
+	addInputEvent(detailLog, res)
+
+	if err != nil {
+		summaryLog.AddError(node, cmd, strconv.Itoa(http.StatusInternalServerError), err.Error())
+	} else {
+		summaryLog.AddSuccess(node, cmd, strconv.Itoa(http.StatusOK), http.StatusText(http.StatusOK))
+	}
+}
+
+func sqlArgs(args []driver.NamedValue) map[string]any {
+	if len(args) == 0 {
+		return nil
+	}
+	values := make(map[string]any, len(args))
+	for _, arg := range args {
+		name := arg.Name
+		if name == "" {
+			name = strconv.Itoa(arg.Ordinal)
+		}
+		value := arg.Value
+		// binary values are not logged
+		if b, ok := value.([]byte); ok {
+			value = fmt.Sprintf("[%d bytes]", len(b))
+		}
+		values[name] = value
+	}
+	return values
+}
+
+// sqlVerb returns the first keyword of query in lower case, or method when
+// there is none.
+func sqlVerb(query, method string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return method
+	}
+	return strings.ToLower(strings.TrimLeft(fields[0], "("))
+}
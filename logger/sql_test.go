@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver answers every exec with one row affected and every query with
+// a single "id" column, failing statements that contain "fail".
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+func (fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errors.New("syntax error")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errors.New("syntax error")
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(2), nil }
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error)  { return &fakeRows{}, nil }
+
+type fakeRows struct{ done bool }
+
+func (*fakeRows) Columns() []string { return []string{"id"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func init() {
+	sql.Register("logger_fake", WrapDriver("users_db", fakeDriver{}))
+}
+
+func TestWrapDriver(t *testing.T) {
+	m, sinks := newSinkTestManager(t)
+	db, err := sql.Open("logger_fake", "")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer db.Close()
+
+	detailLog := m.NewDetailLog("session", "", "create_user")
+	summaryLog := m.NewSummaryLog("session", "", "create_user")
+	ctx := WithSummaryLog(WithDetailLog(context.Background(), detailLog), summaryLog)
+
+	result, err := db.ExecContext(ctx, "INSERT INTO users (name, password) VALUES (?, ?)", "john", "s3cret")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	n, _ := result.RowsAffected()
+	assert.Equal(t, int64(1), n)
+
+	var id int
+	assert.NoError(t, db.QueryRowContext(ctx, "select id from users where name = ?", "john").Scan(&id))
+	assert.Equal(t, 1, id)
+
+	_, err = db.ExecContext(ctx, "fail")
+	assert.Error(t, err)
+
+	// prepared statements are recorded on execution
+	stmt, err := db.PrepareContext(ctx, "DELETE FROM users")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	stmt.ExecContext(ctx)
+	stmt.Close()
+
+	detailLog.End()
+	summaryLog.End("200", "")
+
+	line := detailOutput(t, sinks.detail)
+	outputs := line["Output"].([]interface{})
+	inputs := line["Input"].([]interface{})
+	if len(outputs) != 4 || len(inputs) != 4 {
+		t.Fatalf("Expected 4 operations, but got %d outputs and %d inputs", len(outputs), len(inputs))
+	}
+
+	insert := outputs[0].(map[string]interface{})
+	assert.Equal(t, "users_db.insert", insert["Event"])
+	assert.Equal(t, "sql.exec", insert["Protocol"])
+	assert.Equal(t, map[string]interface{}{
+		"statement": "INSERT INTO users (name, password) VALUES (?, ?)",
+		"args":      map[string]interface{}{"1": "john", "2": "s3cret"},
+	}, insert["Data"])
+
+	inserted := inputs[0].(map[string]interface{})
+	assert.Equal(t, insert["Invoke"], inserted["Invoke"])
+	assert.Equal(t, float64(1), inserted["Data"].(map[string]interface{})["rowsAffected"])
+	assert.Contains(t, inserted["ResTime"], "ms")
+
+	assert.Equal(t, "sql.query", outputs[1].(map[string]interface{})["Protocol"])
+	assert.Equal(t, "syntax error", inputs[2].(map[string]interface{})["Data"].(map[string]interface{})["error"])
+	assert.Equal(t, "users_db.delete", outputs[3].(map[string]interface{})["Event"])
+	assert.Equal(t, float64(2), inputs[3].(map[string]interface{})["Data"].(map[string]interface{})["rowsAffected"])
+
+	var summary LogSummaryEntry
+	json.Unmarshal(sinks.summary.Entries()[0].Payload, &summary)
+	cmds := map[string]string{}
+	for _, seq := range summary.Sequences {
+		assert.Equal(t, "users_db", seq.Node)
+		cmds[seq.Cmd] = seq.Result[0].ResultCode
+	}
+	assert.Equal(t, map[string]string{"insert": "200", "select": "200", "fail": "500", "delete": "200"}, cmds)
+}
+
+func TestWrapDriverMasksArgs(t *testing.T) {
+	sink := NewMemorySink()
+	m, err := New(LogConfig{
+		Detail: DetailLogConfig{Mask: &MaskConfig{Fields: []FieldRule{{Path: "$.args.2"}}}},
+		Sinks:  Sinks{Detail: []Sink{sink}},
+	}, WithDetailRawData(false))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	db := OpenDB("users_db", dsnConnector{driver: fakeDriver{}})
+	defer db.Close()
+
+	detailLog := m.NewDetailLog("session", "", "create_user")
+	ctx := WithDetailLog(context.Background(), detailLog)
+	db.ExecContext(ctx, "INSERT INTO users (name, password) VALUES (?, ?)", "john", "s3cret")
+	detailLog.End()
+
+	line := detailOutput(t, sink)
+	data := line["Output"].([]interface{})[0].(map[string]interface{})["Data"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"1": "john", "2": "******"}, data["args"])
+}
+
+func TestWrapDriverBeginTxOptions(t *testing.T) {
+	db, err := sql.Open("logger_fake", "")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	defer db.Close()
+
+	// fakeConn has no BeginTx, options Begin cannot honour are refused
+	_, err = db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+	assert.EqualError(t, err, "sql: driver does not support non-default isolation level")
+	_, err = db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	assert.EqualError(t, err, "sql: driver does not support read-only transactions")
+	_, err = db.BeginTx(context.Background(), nil)
+	assert.EqualError(t, err, "not supported")
+}
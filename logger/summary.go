@@ -35,6 +35,8 @@ type LogSummaryEntry struct {
 	Session             string         `json:"Session"`
 	InitInvoke          string         `json:"InitInvoke"`
 	Scenario            string         `json:"Scenario"`
+	TraceId             string         `json:"TraceId,omitempty"`
+	SpanId              string         `json:"SpanId,omitempty"`
 	ResponseResult      string         `json:"ResponseResult"`
 	ResponseDesc        string         `json:"ResponseDesc"`
 	Sequences           []Sequences    `json:"Sequences"`
@@ -44,21 +46,32 @@ type LogSummaryEntry struct {
 }
 
 func NewSummaryLog(Session, initInvoke, cmd string) SummaryLog {
+	return defaultManager.NewSummaryLog(Session, initInvoke, cmd)
+}
 
+// NewSummaryLog creates a summary log with the configuration of the Manager.
+func (m *Manager) NewSummaryLog(Session, initInvoke, cmd string) SummaryLog {
+	conf := m.config()
 	if Session == "" {
 		Session = fmt.Sprintf("default_%s", time.Now().Format("20060102150405"))
 	}
 	currentTime := time.Now()
 	if initInvoke == "" {
-		initInvoke = fmt.Sprintf("%s_%s", configLog.ProjectName, currentTime.Format("20060102150405"))
+		initInvoke = fmt.Sprintf("%s_%s", conf.ProjectName, currentTime.Format("20060102150405"))
 	}
-	return &summaryLog{
+	sl := &summaryLog{
 		requestTime: &currentTime,
 		session:     Session,
 		initInvoke:  initInvoke,
 		cmd:         cmd,
-		conf:        configLog,
+		conf:        conf,
+		async:       asyncWriterFor(StreamSummary, conf.ProjectName, conf.Async),
+		streams:     m.streams,
+		metrics:     m.metrics,
+		open:        &m.open,
 	}
+	sl.open.trackSummary(sl)
+	return sl
 }
 
 func (sl *summaryLog) AddField(fieldName string, fieldValue interface{}) {
@@ -90,8 +103,12 @@ func (sl *summaryLog) End(resultCode, resultDescription string) error {
 	if sl.requestTime == nil {
 		return errors.New("summaryLog is already ended")
 	}
+	if sl.metrics != nil {
+		sl.metrics.observe(sl.cmd, resultCode, time.Since(*sl.requestTime), sl.blockDetail)
+	}
 	sl.process(resultCode, resultDescription)
 	sl.requestTime = nil
+	sl.open.untrackSummary(sl)
 	return nil
 }
 
@@ -122,6 +139,10 @@ func (sl *summaryLog) addBlock(node, cmd, resultCode, resultDesc string) {
 }
 
 func (sl *summaryLog) process(responseResult, responseDesc string) {
+	if sl.streams.disabled(StreamSummary) {
+		return
+	}
+
 	endTime := time.Now()
 	elapsed := endTime.Sub(*sl.requestTime)
 
@@ -161,6 +182,8 @@ func (sl *summaryLog) process(responseResult, responseDesc string) {
 		Session:             sl.session,
 		InitInvoke:          sl.initInvoke,
 		Scenario:            sl.cmd,
+		TraceId:             sl.traceID,
+		SpanId:              sl.spanID,
 		ResponseResult:      responseResult,
 		ResponseDesc:        responseDesc,
 		Sequences:           seq,
@@ -172,16 +195,30 @@ func (sl *summaryLog) process(responseResult, responseDesc string) {
 		logEntry.CustomDesc = sl.optionalField
 	}
 
-	b, _ := json.Marshal(logEntry)
-	if sl.conf.Summary.LogConsole {
-		os.Stdout.Write(b)
-		os.Stdout.Write([]byte(endOfLine()))
+	if mask := sl.conf.Summary.Mask; mask != nil {
+		sl.maskEntry(mask, &logEntry)
 	}
 
-	if sl.conf.Summary.LogFile {
-		sl.conf.Summary.LogSummary.Info(string(b))
-	}
+	b, _ := json.Marshal(logEntry)
+	dispatch(sl.async, streamSinks(sl.conf.Summary.LogConsole, sl.conf.Summary.LogFile, sl.conf.Summary.LogSummary, sl.conf.Sinks.Summary), Entry{
+		Stream:  StreamSummary,
+		Time:    endTime,
+		Payload: b,
+	})
+}
 
+func (sl *summaryLog) maskEntry(mask *MaskConfig, logEntry *LogSummaryEntry) {
+	logEntry.ResponseDesc = mask.applyString(logEntry.ResponseDesc)
+	for i := range logEntry.Sequences {
+		for j := range logEntry.Sequences[i].Result {
+			logEntry.Sequences[i].Result[j].ResultDesc = mask.applyString(logEntry.Sequences[i].Result[j].ResultDesc)
+		}
+	}
+	if logEntry.CustomDesc != nil {
+		if fields, ok := mask.Apply(map[string]interface{}(logEntry.CustomDesc)).(map[string]interface{}); ok {
+			logEntry.CustomDesc = fields
+		}
+	}
 }
 
 func getHostname() string {
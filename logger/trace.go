@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	// TraceparentHeader and TracestateHeader are the W3C Trace Context headers.
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+
+	traceContextKey ContextKey = "trace_context"
+)
+
+// TraceContext is a W3C trace context. Its ids are stored in the context
+// under TraceIDKey and SpanIDKey and logged as TraceId and SpanId.
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	// Flags are the trace flags in hex, "01" when sampled.
+	Flags string
+	// State is the tracestate header, passed on unchanged.
+	State string
+}
+
+// NewTraceContext starts a new sampled trace.
+func NewTraceContext() TraceContext {
+	return TraceContext{TraceID: randomHex(16), SpanID: randomHex(8), Flags: "01"}
+}
+
+// ParseTraceparent parses traceparent and tracestate header values.
+func ParseTraceparent(traceparent, tracestate string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, errors.New("traceparent: expected version-traceid-parentid-flags")
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	switch {
+	case !isHex(version, 2) || version == "ff":
+		return TraceContext{}, errors.New("traceparent: invalid version")
+	case version == "00" && len(parts) != 4:
+		return TraceContext{}, errors.New("traceparent: unexpected fields for version 00")
+	case !isHex(traceID, 32) || traceID == strings.Repeat("0", 32):
+		return TraceContext{}, errors.New("traceparent: invalid trace id")
+	case !isHex(spanID, 16) || spanID == strings.Repeat("0", 16):
+		return TraceContext{}, errors.New("traceparent: invalid parent id")
+	case !isHex(flags, 2):
+		return TraceContext{}, errors.New("traceparent: invalid flags")
+	}
+	return TraceContext{TraceID: traceID, SpanID: spanID, Flags: flags, State: strings.TrimSpace(tracestate)}, nil
+}
+
+// Traceparent returns the traceparent header value of tc.
+func (tc TraceContext) Traceparent() string {
+	flags := tc.Flags
+	if flags == "" {
+		flags = "01"
+	}
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + flags
+}
+
+// NewSpan returns a child span of tc: the same trace with a new span id.
+func (tc TraceContext) NewSpan() TraceContext {
+	tc.SpanID = randomHex(8)
+	return tc
+}
+
+// WithTraceContext returns a copy of ctx carrying tc.
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	ctx = context.WithValue(ctx, traceContextKey, tc)
+	ctx = context.WithValue(ctx, TraceIDKey, tc.TraceID)
+	return context.WithValue(ctx, SpanIDKey, tc.SpanID)
+}
+
+// TraceContextFromContext returns the trace context of ctx. Ids set directly
+// under TraceIDKey and SpanIDKey take precedence.
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, _ := ctx.Value(traceContextKey).(TraceContext)
+	if traceID, ok := ctx.Value(TraceIDKey).(string); ok && traceID != tc.TraceID {
+		tc = TraceContext{TraceID: traceID, Flags: "01"}
+	}
+	if spanID, ok := ctx.Value(SpanIDKey).(string); ok {
+		tc.SpanID = spanID
+	}
+	return tc, tc.TraceID != ""
+}
+
+// extractTraceContext returns the span of an inbound request or message:
+// a child of the trace context found with get, or a new trace.
+func extractTraceContext(get func(string) string) TraceContext {
+	tc, err := ParseTraceparent(get(TraceparentHeader), get(TracestateHeader))
+	if err != nil {
+		return NewTraceContext()
+	}
+	return tc.NewSpan()
+}
+
+// injectTraceContext starts a child span of the trace context of ctx for an
+// outbound call and sets its headers with set. Nothing is set when ctx has
+// no trace context.
+func injectTraceContext(ctx context.Context, set func(key, value string)) {
+	tc, ok := TraceContextFromContext(ctx)
+	if !ok {
+		return
+	}
+	tc = tc.NewSpan()
+	set(TraceparentHeader, tc.Traceparent())
+	if tc.State != "" {
+		set(TracestateHeader, tc.State)
+	}
+}
+
+// InjectTraceContext sets the traceparent and tracestate headers of a child
+// span of the trace context of ctx on header.
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	injectTraceContext(ctx, header.Set)
+}
+
+// traceLogs copies the trace and span ids of ctx to the logs of the package.
+func traceLogs(ctx context.Context, d DetailLog, s SummaryLog) {
+	tc, ok := TraceContextFromContext(ctx)
+	if !ok {
+		return
+	}
+	if dl, ok := d.(*detailLog); ok {
+		dl.mu.Lock()
+		dl.TraceId, dl.SpanId = tc.TraceID, tc.SpanID
+		dl.mu.Unlock()
+	}
+	if sl, ok := s.(*summaryLog); ok {
+		sl.mu.Lock()
+		sl.traceID, sl.spanID = tc.TraceID, tc.SpanID
+		sl.mu.Unlock()
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	for {
+		rand.Read(b)
+		// all zero ids are invalid
+		for _, c := range b {
+			if c != 0 {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		expectErr   bool
+	}{
+		{name: "Valid", traceparent: testTraceparent},
+		{name: "Future version with extra fields", traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "Extra fields in version 00", traceparent: testTraceparent + "-extra", expectErr: true},
+		{name: "Invalid version", traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", expectErr: true},
+		{name: "Zero trace id", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", expectErr: true},
+		{name: "Zero span id", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", expectErr: true},
+		{name: "Upper case", traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", expectErr: true},
+		{name: "Empty", traceparent: "", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			trace, err := ParseTraceparent(tc.traceparent, "vendor=value")
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceID)
+			assert.Equal(t, "00f067aa0ba902b7", trace.SpanID)
+			assert.Equal(t, "vendor=value", trace.State)
+		})
+	}
+
+	trace, _ := ParseTraceparent(testTraceparent, "")
+	assert.Equal(t, testTraceparent, trace.Traceparent())
+	child := trace.NewSpan()
+	assert.Equal(t, trace.TraceID, child.TraceID)
+	assert.NotEqual(t, trace.SpanID, child.SpanID)
+}
+
+func TestTraceContextFromContext(t *testing.T) {
+	_, ok := TraceContextFromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := context.WithValue(context.Background(), TraceIDKey, "4bf92f3577b34da6a3ce929d0e0e4736")
+	ctx = context.WithValue(ctx, SpanIDKey, "00f067aa0ba902b7")
+	trace, ok := TraceContextFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, testTraceparent, trace.Traceparent())
+}
+
+func TestMiddlewareTraceContext(t *testing.T) {
+	var downstream http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	detailSink, summarySink, appSink := NewMemorySink(), NewMemorySink(), NewMemorySink()
+	m, err := New(LogConfig{Sinks: Sinks{App: []Sink{appSink}, Detail: []Sink{detailSink}, Summary: []Sink{summarySink}}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	client := &http.Client{Transport: NewTransport(nil, "api", "get")}
+	handler := Middleware("get_user", WithManager(m), WithLogger(m.NewLogger()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NewLog(r.Context()).Info("handling")
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, server.URL, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(TraceparentHeader, testTraceparent)
+	req.Header.Set(TracestateHeader, "vendor=value")
+	captureStdout(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	})
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	detail := detailOutput(t, detailSink)
+	assert.Equal(t, traceID, detail["TraceId"])
+	spanID := detail["SpanId"].(string)
+	assert.Len(t, spanID, 16)
+	assert.NotEqual(t, "00f067aa0ba902b7", spanID, "the service logs its own span")
+
+	var summary LogSummaryEntry
+	json.Unmarshal(summarySink.Entries()[0].Payload, &summary)
+	assert.Equal(t, traceID, summary.TraceId)
+	assert.Equal(t, spanID, summary.SpanId)
+	assert.NotEqual(t, traceID, summary.Session, "the session is not taken from the trace by default")
+	assert.NotEqual(t, "00f067aa0ba902b7", summary.InitInvoke)
+
+	var app map[string]interface{}
+	json.Unmarshal(appSink.Entries()[0].Payload, &app)
+	assert.Equal(t, traceID, app["traceId"])
+	assert.Equal(t, spanID, app["spanId"])
+
+	// the outbound call is a child span of the service span
+	outbound, err := ParseTraceparent(downstream.Get(TraceparentHeader), downstream.Get(TracestateHeader))
+	if err != nil {
+		t.Fatalf("Expected a traceparent downstream, but got %v", err)
+	}
+	assert.Equal(t, traceID, outbound.TraceID)
+	assert.NotEqual(t, spanID, outbound.SpanID)
+	assert.Equal(t, "vendor=value", outbound.State)
+}
+
+func TestGRPCTraceContext(t *testing.T) {
+	serverManager, serverSinks := newSinkTestManager(t)
+	client := startGRPCTestServer(t, serverManager)
+
+	trace := NewTraceContext()
+	ctx := WithTraceContext(context.Background(), trace)
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "users"}); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	server := lastEntry(t, serverSinks.detail)
+	assert.Equal(t, trace.TraceID, server["TraceId"])
+	assert.NotEqual(t, trace.SpanID, server["SpanId"])
+}
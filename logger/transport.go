@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+type transport struct {
+	base http.RoundTripper
+	node string
+	cmd  string
+}
+
+// NewTransport wraps base so every outbound request is recorded on the
+// DetailLog and SummaryLog found in the request context.
+func NewTransport(base http.RoundTripper, node, cmd string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{
+		base: base,
+		node: node,
+		cmd:  cmd,
+	}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	detailLog := DetailLogFromContext(ctx)
+	summaryLog := SummaryLogFromContext(ctx)
+	if _, ok := detailLog.(noopDetailLog); ok {
+		if _, ok := summaryLog.(noopSummaryLog); ok {
+			req = req.Clone(ctx)
+			InjectHeaders(ctx, req.Header)
+			return t.base.RoundTrip(req)
+		}
+	}
+
+	invoke := GenerateXTid(t.node)
+
+	// RoundTrip must not modify the caller's request, so the body is read
+	// into a clone. Only the logged part of the body is buffered, the rest
+	// is streamed.
+	out := req.Clone(ctx)
+	injectHeaders(ctx, out.Header, invoke)
+	limit := bodyLimit(detailLog)
+	var reqBody []byte
+	var reqTruncated bool
+	var err error
+	if out.Body != nil && out.Body != http.NoBody {
+		reqBody, reqTruncated, out.Body, err = captureBody(out.Body, limit)
+		if err != nil {
+			return nil, err
+		}
+		if !reqTruncated {
+			captured := reqBody
+			out.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(captured)), nil
+			}
+		}
+	}
+	reqLength := int64(len(reqBody))
+	if reqTruncated {
+		reqLength = out.ContentLength
+	}
+
+	reqData := OutGoing{
+		Method: out.Method,
+		URL:    out.URL.String(),
+		Header: out.Header,
+		Body:   bodyValue(out.Header.Get(ContentType), reqBody, reqTruncated, reqLength, limit),
+	}
+	detailLog.AddOutputRequest(t.node, t.cmd, invoke, reqData, reqData)
+
+	resp, err := t.base.RoundTrip(out)
+	if err != nil {
+		errData := map[string]interface{}{"error": err.Error()}
+		addInputEvent(detailLog, t.response(invoke, errData, out.Proto, out.Method))
+		summaryLog.AddError(t.node, t.cmd, "error", err.Error())
+		return nil, err
+	}
+
+	var resBody []byte
+	var truncated bool
+	if resp.Body != nil && resp.Body != http.NoBody {
+		resBody, truncated, resp.Body, err = captureBody(resp.Body, limit)
+	}
+	if err != nil {
+		// captureBody closed the body, releasing the connection
+		errData := map[string]interface{}{"error": err.Error()}
+		addInputEvent(detailLog, t.response(invoke, errData, resp.Proto, out.Method))
+		summaryLog.AddError(t.node, t.cmd, "error", err.Error())
+		return nil, err
+	}
+
+	resData := InComing{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       bodyValue(resp.Header.Get(ContentType), resBody, truncated, resp.ContentLength, limit),
+	}
+	addInputEvent(detailLog, t.response(invoke, resData, resp.Proto, out.Method))
+
+	code := strconv.Itoa(resp.StatusCode)
+	if resp.StatusCode >= http.StatusBadRequest {
+		summaryLog.AddError(t.node, t.cmd, code, http.StatusText(resp.StatusCode))
+	} else {
+		summaryLog.AddSuccess(t.node, t.cmd, code, http.StatusText(resp.StatusCode))
+	}
+
+	return resp, nil
+}
+
+// response is the input entry of the response, its ResTime is the time
+// elapsed since the request was logged.
+func (t *transport) response(invoke string, data interface{}, protocol, protocolMethod string) logEvent {
+	return logEvent{node: t.node, cmd: t.cmd, invoke: invoke, logType: "res", data: data, protocol: protocol, protocolMethod: protocolMethod}
+}
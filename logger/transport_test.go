@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+		Detail: DetailLogConfig{
+			RawData: true,
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"name":"john"}`, string(body))
+
+		w.Header().Set(ContentType, ContentTypeJSON)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"failed"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		statusCode int
+		resultCode string
+	}{
+		{
+			name:       "Success response",
+			path:       "/users",
+			statusCode: http.StatusOK,
+			resultCode: "200",
+		},
+		{
+			name:       "Error response",
+			path:       "/fail",
+			statusCode: http.StatusInternalServerError,
+			resultCode: "500",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dl := NewDetailLog("test_session", "test_invoke", "test_scenario").(*detailLog)
+			sl := NewSummaryLog("test_session", "test_invoke", "test_scenario").(*summaryLog)
+			ctx := WithSummaryLog(WithDetailLog(context.Background(), dl), sl)
+
+			client := &http.Client{Transport: NewTransport(nil, "user_service", "create_user")}
+			req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+tc.path, strings.NewReader(`{"name":"john"}`))
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			assert.NotEmpty(t, body, "response body should still be readable")
+			assert.Equal(t, tc.statusCode, resp.StatusCode)
+
+			if len(dl.Output) != 1 || len(dl.Input) != 1 {
+				t.Fatalf("Expected 1 output and 1 input log, but got %d and %d", len(dl.Output), len(dl.Input))
+			}
+
+			out := dl.Output[0]
+			assert.Equal(t, "user_service.create_user", out.Event)
+			assert.Equal(t, "rep", out.Type)
+			outData := out.Data.(map[string]interface{})
+			assert.Equal(t, http.MethodPost, outData["method"])
+			assert.Equal(t, server.URL+tc.path, outData["url"])
+			assert.Equal(t, map[string]interface{}{"name": "john"}, outData["body"])
+
+			in := dl.Input[0]
+			assert.Equal(t, out.Invoke, in.Invoke)
+			assert.Equal(t, "res", in.Type)
+			assert.Equal(t, "HTTP/1.1.POST", *in.Protocol)
+			assert.True(t, strings.HasSuffix(*in.ResTime, " ms"), "ResTime should be a duration, got %s", *in.ResTime)
+			inData := in.Data.(map[string]interface{})
+			assert.Equal(t, float64(tc.statusCode), inData["statusCode"])
+
+			if len(sl.blockDetail) != 1 {
+				t.Fatalf("Expected 1 block, but got %d", len(sl.blockDetail))
+			}
+			assert.Equal(t, "user_service", sl.blockDetail[0].Node)
+			assert.Equal(t, tc.resultCode, sl.blockDetail[0].Result[0].ResultCode)
+		})
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	configLog = LogConfig{
+		ProjectName: "test_project",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	dl := NewDetailLog("test_session", "test_invoke", "test_scenario").(*detailLog)
+	sl := NewSummaryLog("test_session", "test_invoke", "test_scenario").(*summaryLog)
+	ctx := WithSummaryLog(WithDetailLog(context.Background(), dl), sl)
+
+	client := &http.Client{Transport: NewTransport(nil, "user_service", "get_user")}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	_, err := client.Do(req)
+	if err == nil {
+		t.Fatal("Expected error, but got nil")
+	}
+
+	if len(dl.Input) != 1 {
+		t.Fatalf("Expected 1 input log, but got %d", len(dl.Input))
+	}
+	assert.Equal(t, "error", sl.blockDetail[0].Result[0].ResultCode)
+}
+
+func TestTransportWithoutLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, "user_service", "get_user")}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
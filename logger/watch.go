@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// WatchConfig reloads the configuration of the default Manager, see
+// Manager.WatchConfig.
+func WatchConfig(path, envPrefix string, interval time.Duration) (stop func()) {
+	return defaultManager.WatchConfig(path, envPrefix, interval)
+}
+
+// WatchConfig reloads the configuration from path, and the environment
+// variables with envPrefix, when the file changes (checked every interval,
+// disabled when interval is 0) or the process receives SIGHUP. Each reload
+// starts again from the configuration the Manager had when WatchConfig was
+// called, so a key removed from the file falls back to it, and applies the
+// configuration given in code (New, Load, LoadLogConfigFrom) last, so the
+// file and environment never override it. An invalid file is reported on
+// stderr and the current configuration is kept. The returned func stops
+// watching.
+func (m *Manager) WatchConfig(path, envPrefix string, interval time.Duration) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	done := make(chan struct{})
+
+	var tick <-chan time.Time
+	var ticker *time.Ticker
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+	go m.watchConfig(path, envPrefix, m.Config(), tick, ch, done)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			if ticker != nil {
+				ticker.Stop()
+			}
+			close(done)
+		})
+	}
+}
+
+func (m *Manager) watchConfig(path, envPrefix string, base LogConfig, tick <-chan time.Time, reload <-chan os.Signal, done <-chan struct{}) {
+	last := fileVersion(path)
+	for {
+		select {
+		case <-done:
+			return
+		case <-reload:
+			last = fileVersion(path)
+		case <-tick:
+			version := fileVersion(path)
+			if version == last {
+				continue
+			}
+			last = version
+		}
+
+		if err := m.reloadConfig(path, envPrefix, base); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to reload log config: %v\n", err)
+		}
+	}
+}
+
+func (m *Manager) reloadConfig(path, envPrefix string, base LogConfig) error {
+	cfg, err := fileAndEnvConfig(path, envPrefix)
+	if err != nil {
+		return err
+	}
+	return m.replace(base, cfg)
+}
+
+// fileVersion identifies the content of a file by its modification time and
+// size, it is empty when the file cannot be read.
+func fileVersion(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+}
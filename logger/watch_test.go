@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestManagerWatchConfig(t *testing.T) {
+	m, err := New(LogConfig{ProjectName: "test_project"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "log.yaml")
+	writeConfig := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	writeConfig("appLog:\n  logLevel: info\n")
+
+	tick := make(chan time.Time)
+	reload := make(chan os.Signal)
+	done := make(chan struct{})
+	defer close(done)
+	go m.watchConfig(path, "TEST_WATCH", m.Config(), tick, reload, done)
+
+	inFlight := m.NewDetailLog("session", "", "scenario")
+	assert.True(t, inFlight.IsRawDataEnabled())
+
+	// SIGHUP reloads the file
+	writeConfig("appLog:\n  logLevel: error\ndetail:\n  rawData: false\n")
+	reload <- syscall.SIGHUP
+	assert.Eventually(t, func() bool {
+		return !m.Config().Detail.RawData
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, zapcore.ErrorLevel, m.level.Level())
+	assert.False(t, m.NewDetailLog("session", "", "scenario").IsRawDataEnabled())
+	assert.True(t, inFlight.IsRawDataEnabled(), "in-flight logs keep their configuration")
+
+	// an invalid file keeps the current configuration
+	writeConfig("appLog:\n  logLevel: 42\n")
+	reload <- syscall.SIGHUP
+	reload <- syscall.SIGHUP
+	assert.Equal(t, zapcore.ErrorLevel, m.Config().AppLog.LogLevel)
+
+	// a changed file is picked up on the next tick, removed keys fall back
+	writeConfig("detail:\n  logConsole: true\n")
+	tick <- time.Now()
+	assert.Eventually(t, func() bool {
+		return m.Config().Detail.LogConsole
+	}, time.Second, 5*time.Millisecond)
+	assert.True(t, m.Config().Detail.RawData)
+	assert.Equal(t, zapcore.DebugLevel, m.level.Level())
+}
+
+func TestManagerLoad(t *testing.T) {
+	m, err := New(LogConfig{ProjectName: "test_project", Async: &AsyncConfig{BufferSize: 8}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	async := m.Config().Async
+
+	if err := m.Load(LogConfig{Async: &AsyncConfig{BufferSize: 8}}, WithAppLogLevel(zapcore.WarnLevel)); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	assert.Equal(t, zapcore.WarnLevel, m.level.Level())
+	assert.Same(t, async, m.Config().Async, "unchanged async settings keep the running pipeline")
+
+	err = m.Load(LogConfig{AppLog: AppLog{LogLevel: 42}})
+	if err == nil {
+		t.Fatal("Expected error, but got nil")
+	}
+	assert.Equal(t, zapcore.WarnLevel, m.Config().AppLog.LogLevel)
+}
+
+func TestWatchConfigKeepsCodePrecedence(t *testing.T) {
+	m, err := New(LogConfig{ProjectName: "test_project"}, WithAppLogLevel(zapcore.WarnLevel))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "log.yaml")
+	if err := os.WriteFile(path, []byte("appLog:\n  logLevel: error\ndetail:\n  rawData: false\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := m.reloadConfig(path, "TEST_WATCH", m.Config()); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	assert.False(t, m.Config().Detail.RawData, "the file overrides the defaults")
+	assert.Equal(t, zapcore.WarnLevel, m.level.Level(), "the code config overrides the file")
+}
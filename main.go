@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/google/uuid"
 	"github.com/sing3demons/logger-kp/logger"
 )
 
@@ -21,28 +20,17 @@ func main() {
 
 	log := logger.NewLogger()
 
-	http.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
-		logger.InitSession(r.Context(), log)
-
+	mux := http.NewServeMux()
+	mux.Handle("/users", logger.Middleware("get_users", logger.WithLogger(log))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		l := logger.NewLog(r.Context())
 		l.Info("test")
-		node := "client"
-		session := "session:" + uuid.New().String()
-		detailLog := logger.NewDetailLog(session, "", "root")
-		summaryLog := logger.NewSummaryLog(session, "", "root")
-		detailLog.AddInputHttpRequest(node, "get", "invoke", r, false)
-		summaryLog.AddSuccess(node, "get", "invoke", "success")
 
 		data := map[string]interface{}{"message": "success"}
 
-		detailLog.AddOutputRequest(node, "get", "invoke", data, data)
-
+		w.Header().Set(logger.ContentType, logger.ContentTypeJSON)
 		w.WriteHeader(http.StatusOK)
-
 		json.NewEncoder(w).Encode(data)
-		detailLog.End()
-		summaryLog.End("", "success")
-	})
+	})))
 
-	http.ListenAndServe(":8080", nil)
+	http.ListenAndServe(":8080", mux)
 }